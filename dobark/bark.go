@@ -0,0 +1,97 @@
+// Package dobark 通过 Bark 推送通知到 iOS 设备
+//
+// @see https://github.com/Finb/Bark
+package dobark
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/donething/utils-go/dohttp"
+)
+
+// Addr 官方服务的地址
+const Addr = "https://api.day.app"
+
+// 通知的级别
+const (
+	LevelActive        = "active"
+	LevelTimeSensitive = "timeSensitive" // 专注模式下仍显示
+	LevelPassive       = "passive"       // 只添加到通知列表，不亮屏
+	LevelCritical      = "critical"      // 重要警告，静音时也响铃
+)
+
+// Msg 推送的通知
+type Msg struct {
+	Title    string `json:"title,omitempty"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Body     string `json:"body"`
+	// 级别，如 LevelTimeSensitive
+	Level string `json:"level,omitempty"`
+	// 重要警告的音量，0 到 10
+	Volume int    `json:"volume,omitempty"`
+	Badge  int    `json:"badge,omitempty"`
+	Sound  string `json:"sound,omitempty"`
+	Icon   string `json:"icon,omitempty"`
+	// 分组
+	Group string `json:"group,omitempty"`
+	// 点击通知后打开的链接
+	Url string `json:"url,omitempty"`
+	// 是否保存到历史记录，"1"保存、"0"不保存
+	IsArchive string `json:"isArchive,omitempty"`
+}
+
+// Bark 推送对象
+type Bark struct {
+	addr   string
+	key    string
+	client dohttp.DoClient
+}
+
+// 推送的响应
+type pushResult struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewBark 创建推送对象
+//
+// key 设备的 key，即 App 中推送地址"https://api.day.app/xxx/"中的"xxx"
+func NewBark(key string) *Bark {
+	return &Bark{addr: Addr, key: key, client: dohttp.New(false, false)}
+}
+
+// SetAddr 设置服务的地址，如自建服务、测试用的模拟服务
+//
+// addr 如 "http://127.0.0.1:8080"，末尾不含"/"
+func (b *Bark) SetAddr(addr string) {
+	b.addr = addr
+}
+
+// Push 推送通知
+func (b *Bark) Push(msg *Msg) error {
+	data := struct {
+		*Msg
+		DeviceKey string `json:"device_key"`
+	}{Msg: msg, DeviceKey: b.key}
+
+	bs, err := b.client.PostJSONObj(b.addr+"/push", data, nil)
+	if err != nil {
+		return fmt.Errorf("推送时网络出错：%w", err)
+	}
+
+	var result pushResult
+	err = json.Unmarshal(bs, &result)
+	if err != nil {
+		return fmt.Errorf("解析推送响应 JSON 文本时出错：%w：%s", err, string(bs))
+	}
+	if result.Code != 200 {
+		return fmt.Errorf("推送时出错：%s", string(bs))
+	}
+
+	return nil
+}
+
+// PushText 推送文本通知
+func (b *Bark) PushText(title string, body string) error {
+	return b.Push(&Msg{Title: title, Body: body})
+}
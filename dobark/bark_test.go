@@ -0,0 +1,37 @@
+package dobark
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBark_Push(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		if r.URL.Path != "/push" || got["device_key"] != "key" {
+			w.Write([]byte(`{"code":400,"message":"failed to get device token"}`))
+			return
+		}
+		w.Write([]byte(`{"code":200,"message":"success"}`))
+	}))
+	defer srv.Close()
+
+	bark := NewBark("key")
+	bark.SetAddr(srv.URL)
+	err := bark.Push(&Msg{Title: "标题", Body: "正文", Level: LevelTimeSensitive, Group: "任务"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["title"] != "标题" || got["level"] != LevelTimeSensitive || got["group"] != "任务" {
+		t.Errorf("推送的数据不符：%v", got)
+	}
+
+	bark = NewBark("bad")
+	bark.SetAddr(srv.URL)
+	if err = bark.PushText("标题", "正文"); err == nil {
+		t.Errorf("key 错误时应返回错误")
+	}
+}
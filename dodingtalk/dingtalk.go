@@ -0,0 +1,148 @@
+// Package dodingtalk 通过钉钉群机器人推送消息
+//
+// @see https://open.dingtalk.com/document/orgapp/custom-robots-send-group-messages
+package dodingtalk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/donething/utils-go/dohttp"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Addr 钉钉接口的地址
+const Addr = "https://oapi.dingtalk.com"
+
+// Robot 钉钉群机器人
+type Robot struct {
+	addr   string
+	token  string
+	secret string
+	client dohttp.DoClient
+}
+
+// At 提醒群成员
+type At struct {
+	AtMobiles []string `json:"atMobiles,omitempty"`
+	AtUserIds []string `json:"atUserIds,omitempty"`
+	IsAtAll   bool     `json:"isAtAll,omitempty"`
+}
+
+// MsgText 文本消息
+type MsgText struct {
+	Content string `json:"content"`
+}
+
+// MsgMarkdown Markdown 消息
+type MsgMarkdown struct {
+	// 会话列表中显示的标题
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// MsgLink 链接消息
+type MsgLink struct {
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	MessageUrl string `json:"messageUrl"`
+	PicUrl     string `json:"picUrl,omitempty"`
+}
+
+// MsgActionCard 卡片消息，整体跳转
+type MsgActionCard struct {
+	Title       string `json:"title"`
+	Text        string `json:"text"`
+	SingleTitle string `json:"singleTitle"`
+	SingleURL   string `json:"singleURL"`
+}
+
+// Msg 推送的消息。按 Msgtype 设置对应的内容
+type Msg struct {
+	Msgtype    string         `json:"msgtype"` // "text"、"markdown"、"link"、"actionCard"
+	Text       *MsgText       `json:"text,omitempty"`
+	Markdown   *MsgMarkdown   `json:"markdown,omitempty"`
+	Link       *MsgLink       `json:"link,omitempty"`
+	ActionCard *MsgActionCard `json:"actionCard,omitempty"`
+	At         *At            `json:"at,omitempty"`
+}
+
+// 推送的响应
+type pushResult struct {
+	Errcode int    `json:"errcode"`
+	Errmsg  string `json:"errmsg"`
+}
+
+// NewRobot 创建群机器人
+//
+// token webhook 地址中的 access_token
+//
+// secret 安全设置为“加签”时的密钥（以"SEC"开头），未设置时传空""
+func NewRobot(token string, secret string) *Robot {
+	return &Robot{addr: Addr, token: token, secret: secret, client: dohttp.New(false, false)}
+}
+
+// SetAddr 设置接口的地址，如测试用的模拟服务
+//
+// addr 如 "http://127.0.0.1:8080"，末尾不含"/"
+func (r *Robot) SetAddr(addr string) {
+	r.addr = addr
+}
+
+// Sign 计算签名：以 secret 为密钥，对"timestamp\nsecret"计算 HmacSHA256，再进行 base64 编码
+//
+// timestamp 毫秒时间戳，与服务器的时间相差不能超过 1 小时
+func Sign(timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Push 推送消息。每个机器人每分钟最多发送 20 条
+func (r *Robot) Push(msg *Msg) error {
+	query := url.Values{"access_token": {r.token}}
+	if r.secret != "" {
+		ts := time.Now().UnixMilli()
+		query.Set("timestamp", strconv.FormatInt(ts, 10))
+		query.Set("sign", Sign(ts, r.secret))
+	}
+
+	bs, err := r.client.PostJSONObj(r.addr+"/robot/send?"+query.Encode(), msg, nil)
+	if err != nil {
+		return fmt.Errorf("推送时网络出错：%w", err)
+	}
+
+	var result pushResult
+	err = json.Unmarshal(bs, &result)
+	if err != nil {
+		return fmt.Errorf("解析推送响应 JSON 文本时出错：%w：%s", err, string(bs))
+	}
+	if result.Errcode != 0 {
+		return fmt.Errorf("推送时出错：%s", string(bs))
+	}
+
+	return nil
+}
+
+// PushText 推送文本消息
+//
+// at 提醒的成员，可为 nil
+func (r *Robot) PushText(content string, at *At) error {
+	return r.Push(&Msg{Msgtype: "text", Text: &MsgText{Content: content}, At: at})
+}
+
+// PushMarkdown 推送 Markdown 消息
+//
+// at 提醒的成员，可为 nil。需在 text 中包含"@手机号"才会提醒
+func (r *Robot) PushMarkdown(title string, text string, at *At) error {
+	return r.Push(&Msg{Msgtype: "markdown", Markdown: &MsgMarkdown{Title: title, Text: text}, At: at})
+}
+
+// PushLink 推送链接消息
+func (r *Robot) PushLink(link *MsgLink) error {
+	return r.Push(&Msg{Msgtype: "link", Link: link})
+}
@@ -0,0 +1,48 @@
+package dodingtalk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	// 与按官方文档的 Python 示例计算的结果一致
+	sign := Sign(1577262236757, "this is secret")
+	if sign != "hmPWwU+7lVdm3ZZz0r9tSfx0L4Q26jWOZr9+Gs6EZQM=" {
+		t.Errorf("签名不符：%s", sign)
+	}
+}
+
+func TestRobot_PushText(t *testing.T) {
+	var msg Msg
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		ts, _ := strconv.ParseInt(query.Get("timestamp"), 10, 64)
+		if query.Get("access_token") != "token" || query.Get("sign") != Sign(ts, "SECxxx") {
+			w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	robot := NewRobot("token", "SECxxx")
+	robot.SetAddr(srv.URL)
+	err := robot.PushText("测试", &At{AtMobiles: []string{"13800000000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Msgtype != "text" || msg.Text.Content != "测试" || msg.At.AtMobiles[0] != "13800000000" {
+		t.Errorf("推送的消息不符：%+v", msg)
+	}
+
+	robot = NewRobot("token", "SECbad")
+	robot.SetAddr(srv.URL)
+	if err = robot.PushMarkdown("标题", "正文", nil); err == nil {
+		t.Errorf("签名错误时应返回错误")
+	}
+}
@@ -0,0 +1,125 @@
+// Package dofeishu 通过飞书群机器人推送消息
+//
+// @see https://open.feishu.cn/document/client-docs/bot-v3/add-custom-bot
+package dofeishu
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/donething/utils-go/dohttp"
+	"strconv"
+	"time"
+)
+
+// Addr 飞书接口的地址
+const Addr = "https://open.feishu.cn"
+
+// Robot 飞书群机器人
+type Robot struct {
+	addr   string
+	token  string
+	secret string
+	client dohttp.DoClient
+}
+
+// PostElement 富文本中的元素
+type PostElement struct {
+	Tag    string `json:"tag"` // "text"、"a"、"at"、"img"
+	Text   string `json:"text,omitempty"`
+	Href   string `json:"href,omitempty"`
+	UserID string `json:"user_id,omitempty"` // "all"表示所有人
+}
+
+// PostContent 富文本的内容，每行为一组元素
+type PostContent struct {
+	Title   string          `json:"title"`
+	Content [][]PostElement `json:"content"`
+}
+
+// Msg 推送的消息
+type Msg struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Sign      string `json:"sign,omitempty"`
+	MsgType   string `json:"msg_type"` // "text"、"post"、"interactive"
+	// 文本、富文本消息的内容
+	Content interface{} `json:"content,omitempty"`
+	// 卡片消息的内容
+	Card interface{} `json:"card,omitempty"`
+}
+
+// 推送的响应
+type pushResult struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// NewRobot 创建群机器人
+//
+// token webhook 地址"https://open.feishu.cn/open-apis/bot/v2/hook/xxx"中的"xxx"
+//
+// secret 安全设置为“签名校验”时的密钥，未设置时传空""
+func NewRobot(token string, secret string) *Robot {
+	return &Robot{addr: Addr, token: token, secret: secret, client: dohttp.New(false, false)}
+}
+
+// SetAddr 设置接口的地址，如测试用的模拟服务
+//
+// addr 如 "http://127.0.0.1:8080"，末尾不含"/"
+func (r *Robot) SetAddr(addr string) {
+	r.addr = addr
+}
+
+// Sign 计算签名：以"timestamp\nsecret"为密钥，对空数据计算 HmacSHA256，再进行 base64 编码
+//
+// timestamp 秒级时间戳，与服务器的时间相差不能超过 1 小时
+func Sign(timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Push 推送消息。设置了密钥时，将自动签名
+func (r *Robot) Push(msg *Msg) error {
+	if r.secret != "" {
+		ts := time.Now().Unix()
+		msg.Timestamp = strconv.FormatInt(ts, 10)
+		msg.Sign = Sign(ts, r.secret)
+	}
+
+	bs, err := r.client.PostJSONObj(r.addr+"/open-apis/bot/v2/hook/"+r.token, msg, nil)
+	if err != nil {
+		return fmt.Errorf("推送时网络出错：%w", err)
+	}
+
+	var result pushResult
+	err = json.Unmarshal(bs, &result)
+	if err != nil {
+		return fmt.Errorf("解析推送响应 JSON 文本时出错：%w：%s", err, string(bs))
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("推送时出错：%s", string(bs))
+	}
+
+	return nil
+}
+
+// PushText 推送文本消息。可用`<at user_id="all">所有人</at>`提醒成员
+func (r *Robot) PushText(text string) error {
+	return r.Push(&Msg{MsgType: "text", Content: map[string]string{"text": text}})
+}
+
+// PushPost 推送富文本消息
+func (r *Robot) PushPost(post *PostContent) error {
+	return r.Push(&Msg{MsgType: "post", Content: map[string]interface{}{
+		"post": map[string]*PostContent{"zh_cn": post},
+	}})
+}
+
+// PushCard 推送卡片消息
+//
+// card 卡片的 JSON 结构，可用飞书的卡片搭建工具生成
+func (r *Robot) PushCard(card interface{}) error {
+	return r.Push(&Msg{MsgType: "interactive", Card: card})
+}
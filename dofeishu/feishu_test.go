@@ -0,0 +1,51 @@
+package dofeishu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	// 与按官方文档的 Python 示例计算的结果一致
+	sign := Sign(1577262236, "this is secret")
+	if sign != "RAfCltg8rVdmpPK5kCz1M40hrj/YzGYnkc/3vXwYNts=" {
+		t.Errorf("签名不符：%s", sign)
+	}
+}
+
+func TestRobot_PushText(t *testing.T) {
+	var msg struct {
+		Timestamp string            `json:"timestamp"`
+		Sign      string            `json:"sign"`
+		MsgType   string            `json:"msg_type"`
+		Content   map[string]string `json:"content"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&msg)
+		ts, _ := strconv.ParseInt(msg.Timestamp, 10, 64)
+		if r.URL.Path != "/open-apis/bot/v2/hook/token" || msg.Sign != Sign(ts, "secret") {
+			w.Write([]byte(`{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"msg":"success","data":{}}`))
+	}))
+	defer srv.Close()
+
+	robot := NewRobot("token", "secret")
+	robot.SetAddr(srv.URL)
+	if err := robot.PushText("测试"); err != nil {
+		t.Fatal(err)
+	}
+	if msg.MsgType != "text" || msg.Content["text"] != "测试" {
+		t.Errorf("推送的消息不符：%+v", msg)
+	}
+
+	robot = NewRobot("token", "bad")
+	robot.SetAddr(srv.URL)
+	if err := robot.PushText("测试"); err == nil {
+		t.Errorf("签名错误时应返回错误")
+	}
+}
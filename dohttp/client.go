@@ -40,7 +40,8 @@ type DoClient struct {
 
 // New 初始化 DoClient
 func New(needCookieJar bool, checkSSL bool) DoClient {
-	c := &http.Client{Transport: http.DefaultTransport}
+	// 复制默认的 Transport，以免设置代理等操作影响其它客户端
+	c := &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
 
 	// 设置超时时间
 	dialer := &net.Dialer{
@@ -0,0 +1,398 @@
+// Package domail 通过 SMTP 发送邮件，支持 STARTTLS、SSL(465 端口)、HTML 正文和附件
+//
+// mailer := domail.NewMailer("smtp.example.com", 587, "me@example.com", "password")
+// err := mailer.Send(&domail.Mail{To: []string{"you@example.com"}, Subject: "标题", Body: "正文"})
+package domail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoTLS 服务器不支持 STARTTLS，且未允许明文发送
+	ErrNoTLS = errors.New("服务器不支持 STARTTLS")
+)
+
+// Mail 一封邮件
+type Mail struct {
+	To  []string
+	Cc  []string
+	Bcc []string
+
+	Subject string
+	Body    string
+	// 正文是否为 HTML
+	HTML bool
+
+	// 附件文件的路径
+	Attachments []string
+}
+
+// Mailer SMTP 发件客户端
+type Mailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+
+	// 服务器不支持 STARTTLS 时，是否允许明文发送
+	allowPlain bool
+	tlsConfig  *tls.Config
+	// 每个 SMTP 阶段（连接、登录、设置收件人、发送内容等）的超时时长
+	timeout time.Duration
+}
+
+// NewMailer 创建发件客户端
+//
+// port 为 465 时使用 SSL 连接，其它端口（如 25、587）在服务器支持时使用 STARTTLS
+//
+// username 为空""时不登录；发件人默认为 username
+func NewMailer(host string, port int, username string, password string) *Mailer {
+	return &Mailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     username,
+		timeout:  30 * time.Second,
+	}
+}
+
+// SetFrom 设置发件人，如"name@example.com"或"名称 <name@example.com>"
+func (m *Mailer) SetFrom(from string) {
+	m.from = from
+}
+
+// SetTLSConfig 设置 TLS 的配置，如自签名证书的根证书
+func (m *Mailer) SetTLSConfig(cfg *tls.Config) {
+	m.tlsConfig = cfg
+}
+
+// SetTimeout 设置每个 SMTP 阶段的超时时长，默认 30 秒。为 0 时不超时
+//
+// 发送邮件内容时，超时只限制每次写入的 32KB 数据，所以附件较大、网络较慢时也不会因超时而失败
+func (m *Mailer) SetTimeout(d time.Duration) {
+	m.timeout = d
+}
+
+// AllowPlain 设置服务器不支持 STARTTLS 时，是否允许明文发送。默认不允许
+func (m *Mailer) AllowPlain(allow bool) {
+	m.allowPlain = allow
+}
+
+// Send 发送邮件
+func (m *Mailer) Send(mail *Mail) error {
+	from, err := parseAddr(m.from)
+	if err != nil {
+		return fmt.Errorf("发件人'%s'的格式错误：%w", m.from, err)
+	}
+
+	rcpts := make([]string, 0, len(mail.To)+len(mail.Cc)+len(mail.Bcc))
+	for _, list := range [][]string{mail.To, mail.Cc, mail.Bcc} {
+		for _, a := range list {
+			addr, err := parseAddr(a)
+			if err != nil {
+				return fmt.Errorf("收件人'%s'的格式错误：%w", a, err)
+			}
+			rcpts = append(rcpts, addr)
+		}
+	}
+	if len(rcpts) == 0 {
+		return fmt.Errorf("没有收件人")
+	}
+
+	msg, err := BuildMessage(m.from, mail)
+	if err != nil {
+		return err
+	}
+
+	c, conn, err := m.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if m.username != "" {
+		if err = m.extend(conn); err != nil {
+			return err
+		}
+		err = c.Auth(smtp.PlainAuth("", m.username, m.password, m.host))
+		if err != nil {
+			return fmt.Errorf("登录出错：%w", err)
+		}
+	}
+
+	if err = m.extend(conn); err != nil {
+		return err
+	}
+	if err = c.Mail(from); err != nil {
+		return fmt.Errorf("设置发件人出错：%w", err)
+	}
+	for _, rcpt := range rcpts {
+		if err = c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("设置收件人'%s'出错：%w", rcpt, err)
+		}
+	}
+
+	if err = m.extend(conn); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件内容出错：%w", err)
+	}
+	// 分块写入，每块前都延长超时，以免附件较大时超时
+	for len(msg) != 0 {
+		n := dataChunk
+		if n > len(msg) {
+			n = len(msg)
+		}
+		if err = m.extend(conn); err != nil {
+			return err
+		}
+		if _, err = w.Write(msg[:n]); err != nil {
+			return fmt.Errorf("发送邮件内容出错：%w", err)
+		}
+		msg = msg[n:]
+	}
+	if err = m.extend(conn); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("发送邮件内容出错：%w", err)
+	}
+
+	if err = m.extend(conn); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// 发送邮件内容时，每次写入的字节数
+const dataChunk = 32 * 1024
+
+// 为下一个 SMTP 阶段重新设置连接的超时
+//
+// Dialer 的超时只作用于连接阶段，之后服务器无响应时也需超时，以免一直阻塞
+func (m *Mailer) extend(conn net.Conn) error {
+	if m.timeout <= 0 {
+		return nil
+	}
+	if err := conn.SetDeadline(time.Now().Add(m.timeout)); err != nil {
+		return fmt.Errorf("设置超时出错：%w", err)
+	}
+	return nil
+}
+
+// 连接服务器，并按需开启 TLS。同时返回底层的连接，以便设置各阶段的超时
+func (m *Mailer) dial() (*smtp.Client, net.Conn, error) {
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	cfg := m.tlsConfig.Clone()
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = m.host
+	}
+
+	dialer := &net.Dialer{Timeout: m.timeout}
+	var conn net.Conn
+	var err error
+	if m.port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, cfg)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("连接服务器出错：%w", err)
+	}
+	if err = m.extend(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("连接服务器出错：%w", err)
+	}
+
+	if m.port != 465 {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			err = c.StartTLS(cfg)
+			if err != nil {
+				c.Close()
+				return nil, nil, fmt.Errorf("开启 STARTTLS 出错：%w", err)
+			}
+		} else if !m.allowPlain {
+			c.Close()
+			return nil, nil, ErrNoTLS
+		}
+	}
+
+	return c, conn, nil
+}
+
+// BuildMessage 生成邮件的 MIME 内容。不包含密送人
+//
+// 地址的格式错误（如包含换行符）时返回错误
+func BuildMessage(from string, mail *Mail) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(k string, v string) {
+		buf.WriteString(k + ": " + v + "\r\n")
+	}
+
+	fromHeader, err := encodeAddr(from)
+	if err != nil {
+		return nil, fmt.Errorf("发件人'%s'的格式错误：%w", from, err)
+	}
+	toHeader, err := encodeAddrs(mail.To)
+	if err != nil {
+		return nil, err
+	}
+	header("From", fromHeader)
+	// 只有抄送、密送人时，不写入空的 To
+	if len(mail.To) != 0 {
+		header("To", toHeader)
+	}
+	if len(mail.Cc) != 0 {
+		ccHeader, err := encodeAddrs(mail.Cc)
+		if err != nil {
+			return nil, err
+		}
+		header("Cc", ccHeader)
+	}
+	header("Subject", mime.BEncoding.Encode("UTF-8", mail.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+
+	contentType := "text/plain; charset=UTF-8"
+	if mail.HTML {
+		contentType = "text/html; charset=UTF-8"
+	}
+
+	// 无附件时，只有正文
+	if len(mail.Attachments) == 0 {
+		header("Content-Type", contentType)
+		header("Content-Transfer-Encoding", "base64")
+		buf.WriteString("\r\n")
+		writeBase64(&buf, []byte(mail.Body))
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+	buf.WriteString("\r\n")
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, []byte(mail.Body))
+
+	for _, path := range mail.Attachments {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取附件'%s'出错：%w", path, err)
+		}
+
+		name := filepath.Base(path)
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		part, err = writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(ctype, map[string]string{"name": name})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, bs)
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// 以每行 76 个字符写入 base64 编码的内容
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// 提取地址中的邮箱，如"名称 <a@example.com>"中的"a@example.com"
+//
+// 按 RFC 5322 解析，包含换行符等非法字符时返回错误，避免被注入邮件头、SMTP 命令
+func parseAddr(addr string) (string, error) {
+	a, err := netmail.ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	return a.Address, nil
+}
+
+// 编码地址中的名称，如"名称 <a@example.com>"
+func encodeAddr(addr string) (string, error) {
+	a, err := netmail.ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
+
+// 编码多个地址
+func encodeAddrs(addrs []string) (string, error) {
+	encoded := make([]string, len(addrs))
+	for i, a := range addrs {
+		e, err := encodeAddr(a)
+		if err != nil {
+			return "", fmt.Errorf("收件人'%s'的格式错误：%w", a, err)
+		}
+		encoded[i] = e
+	}
+	return strings.Join(encoded, ", "), nil
+}
+
+// 生成 Message-ID
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := parseAddr(from); err == nil {
+		domain = addr[strings.LastIndex(addr, "@")+1:]
+	}
+
+	random := make([]byte, 8)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain)
+}
@@ -0,0 +1,309 @@
+package domail
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 本地的模拟 SMTP 服务
+type fakeSMTP struct {
+	ln       net.Listener
+	tlsConf  *tls.Config
+	startTLS bool
+	// 每条命令响应前的延迟
+	delay time.Duration
+
+	mu      sync.Mutex
+	usedTLS bool
+	auth    string
+	from    string
+	rcpts   []string
+	data    []byte
+}
+
+// 启动模拟 SMTP 服务。startTLS 为是否支持 STARTTLS，返回信任其证书的根证书
+func newFakeSMTP(t *testing.T, startTLS bool) (*fakeSMTP, *x509.CertPool) {
+	// 借用 httptest 的证书，其对 127.0.0.1 有效
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	conf := &tls.Config{Certificates: ts.TLS.Certificates}
+	ts.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln, tlsConf: conf, startTLS: startTLS}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s, pool
+}
+
+func (s *fakeSMTP) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// 处理一个连接
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake ESMTP")
+
+	secure := false
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		time.Sleep(s.delay)
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		arg := strings.TrimSpace(strings.TrimPrefix(line, strings.SplitN(line, " ", 2)[0]))
+
+		s.mu.Lock()
+		switch cmd {
+		case "EHLO", "HELO":
+			if s.startTLS && !secure {
+				tp.PrintfLine("250-fake")
+				tp.PrintfLine("250-STARTTLS")
+			} else {
+				tp.PrintfLine("250-fake")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConf)
+			if err = tlsConn.Handshake(); err != nil {
+				s.mu.Unlock()
+				return
+			}
+			conn, secure, s.usedTLS = tlsConn, true, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			bs, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			s.auth = string(bs)
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			s.from = arg
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			s.rcpts = append(s.rcpts, arg)
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			s.data, _ = tp.ReadDotBytes()
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestMailer_Send(t *testing.T) {
+	srv, pool := newFakeSMTP(t, true)
+
+	path := filepath.Join(t.TempDir(), "报告.txt")
+	if err := os.WriteFile(path, []byte("report content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mailer := NewMailer("127.0.0.1", srv.port(), "me@example.com", "pass")
+	mailer.SetFrom("测试 <me@example.com>")
+	mailer.SetTLSConfig(&tls.Config{RootCAs: pool})
+	err := mailer.Send(&Mail{
+		To:          []string{"you@example.com"},
+		Bcc:         []string{"<hidden@example.com>"},
+		Subject:     "测试标题",
+		Body:        "测试正文",
+		Attachments: []string{path},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !srv.usedTLS || srv.auth != "\x00me@example.com\x00pass" || len(srv.rcpts) != 2 {
+		t.Errorf("会话不符：TLS %v，登录 %q，收件人 %v", srv.usedTLS, srv.auth, srv.rcpts)
+	}
+
+	// 解析邮件
+	msg, err := mail.ReadMessage(strings.NewReader(string(srv.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "测试标题" || msg.Header.Get("Bcc") != "" {
+		t.Errorf("邮件头不符：%v", msg.Header)
+	}
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	parts := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, bufio.NewReader(part)))
+		parts[part.FileName()] = string(bs)
+	}
+	if parts[""] != "测试正文" || parts["报告.txt"] != "report content" {
+		t.Errorf("邮件内容不符：%v", parts)
+	}
+}
+
+func TestMailer_NoTLS(t *testing.T) {
+	srv, _ := newFakeSMTP(t, false)
+
+	mailer := NewMailer("127.0.0.1", srv.port(), "", "")
+	mailer.SetFrom("me@example.com")
+	m := &Mail{To: []string{"you@example.com"}, Subject: "subject", Body: "body"}
+	if err := mailer.Send(m); !errors.Is(err, ErrNoTLS) {
+		t.Errorf("应返回 ErrNoTLS，实际为 %v", err)
+	}
+
+	mailer.AllowPlain(true)
+	if err := mailer.Send(m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMailer_InvalidAddr(t *testing.T) {
+	srv, _ := newFakeSMTP(t, false)
+
+	mailer := NewMailer("127.0.0.1", srv.port(), "", "")
+	mailer.AllowPlain(true)
+	mailer.SetFrom("me@example.com")
+
+	// 地址中包含换行符时，可能被注入邮件头、SMTP 命令
+	for _, to := range []string{
+		"you@example.com\r\nBcc: evil@example.com",
+		"名称\n <you@example.com>",
+		"you@example.com>\r\nRCPT TO:<evil@example.com",
+		"no-at-sign",
+	} {
+		if err := mailer.Send(&Mail{To: []string{to}, Subject: "subject", Body: "body"}); err == nil {
+			t.Errorf("收件人 %q 的格式错误，应返回错误", to)
+		}
+	}
+
+	mailer.SetFrom("me@example.com\r\nX-Injected: 1")
+	if err := mailer.Send(&Mail{To: []string{"you@example.com"}, Subject: "subject", Body: "body"}); err == nil {
+		t.Errorf("发件人的格式错误，应返回错误")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.rcpts) != 0 {
+		t.Errorf("格式错误时不应发送，实际收件人 %v", srv.rcpts)
+	}
+}
+
+func TestMailer_Timeout(t *testing.T) {
+	// 接受连接，但不响应
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	mailer := NewMailer("127.0.0.1", ln.Addr().(*net.TCPAddr).Port, "", "")
+	mailer.SetTimeout(200 * time.Millisecond)
+	done := make(chan error, 1)
+	go func() {
+		done <- mailer.Send(&Mail{To: []string{"you@example.com"}, Subject: "subject", Body: "body"})
+	}()
+
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("服务器无响应时应返回错误")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("服务器无响应时未超时")
+	}
+}
+
+func TestMailer_TimeoutPerPhase(t *testing.T) {
+	srv, _ := newFakeSMTP(t, false)
+	// 每条命令都较慢，整个会话超过超时时长，但每个阶段都在其内
+	srv.delay = 100 * time.Millisecond
+
+	mailer := NewMailer("127.0.0.1", srv.port(), "", "")
+	mailer.AllowPlain(true)
+	mailer.SetFrom("me@example.com")
+	mailer.SetTimeout(300 * time.Millisecond)
+	err := mailer.Send(&Mail{To: []string{"you@example.com"}, Subject: "subject", Body: "body"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMailer_BccOnly(t *testing.T) {
+	srv, _ := newFakeSMTP(t, false)
+
+	mailer := NewMailer("127.0.0.1", srv.port(), "", "")
+	mailer.AllowPlain(true)
+	mailer.SetFrom("me@example.com")
+	if err := mailer.Send(&Mail{Bcc: []string{"hidden@example.com"}, Subject: "subject", Body: "body"}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	msg, err := mail.ReadMessage(strings.NewReader(string(srv.data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.Header["To"]; ok || len(srv.rcpts) != 1 {
+		t.Errorf("只有密送人时不应有 To 头：%v，收件人 %v", msg.Header, srv.rcpts)
+	}
+}
@@ -0,0 +1,196 @@
+// Package donotify 统一的通知接口，将同一条通知发送到 TG、微信等多个渠道
+//
+// 各渠道的适配器负责将通知转换为该渠道的格式（如 TG 的 Markdown V2、企业微信的 Markdown）
+//
+// multi := donotify.NewMulti()
+// multi.Add("tg", donotify.NewTG(bot, chatID))
+// multi.Add("qiye", donotify.NewQiYe(qy, agentid, ""))
+// err := multi.Notify(&donotify.Notification{Title: "任务失败", Body: "...", Severity: donotify.SeverityError})
+package donotify
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Severity 通知的严重程度
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+// String 严重程度的名称
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "信息"
+	case SeverityWarning:
+		return "警告"
+	case SeverityError:
+		return "错误"
+	case SeverityCritical:
+		return "严重"
+	default:
+		return fmt.Sprintf("未知(%d)", int(s))
+	}
+}
+
+// Emoji 表示严重程度的符号，可放在标题前
+func (s Severity) Emoji() string {
+	switch s {
+	case SeverityWarning:
+		return "⚠️"
+	case SeverityError:
+		return "❌"
+	case SeverityCritical:
+		return "🚨"
+	default:
+		return "ℹ️"
+	}
+}
+
+// Attachment 通知的附件
+type Attachment struct {
+	// 附件文件的路径
+	Path string
+	// 显示的文件名。为空时使用路径中的文件名
+	Name string
+}
+
+// FileName 显示的文件名
+func (a *Attachment) FileName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return filepath.Base(a.Path)
+}
+
+// Notification 一条通知
+type Notification struct {
+	Title string
+	// 正文，纯文本，由各渠道转义后发送
+	Body     string
+	Severity Severity
+	// 相关链接，可空
+	Link string
+	// 附件，可空。不支持附件的渠道，将在正文后列出文件名
+	Attachments []Attachment
+}
+
+// 附件的文件名，以"、"分隔
+func (n *Notification) attachmentNames() string {
+	names := make([]string, len(n.Attachments))
+	for i := range n.Attachments {
+		names[i] = n.Attachments[i].FileName()
+	}
+	return strings.Join(names, "、")
+}
+
+// Notifier 通知的渠道
+type Notifier interface {
+	Notify(n *Notification) error
+}
+
+// NotifierFunc 将函数作为渠道
+type NotifierFunc func(n *Notification) error
+
+// Notify 发送通知
+func (f NotifierFunc) Notify(n *Notification) error {
+	return f(n)
+}
+
+// MultiError 部分渠道发送失败
+type MultiError struct {
+	// 键为渠道名
+	Failures map[string]error
+}
+
+func (e *MultiError) Error() string {
+	names := make([]string, 0, len(e.Failures))
+	for name := range e.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("[%s]%s", name, e.Failures[name])
+	}
+	return fmt.Sprintf("%d 个渠道发送失败：%s", len(names), strings.Join(msgs, "；"))
+}
+
+// Unwrap 各渠道的错误
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, err := range e.Failures {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Multi 将通知同时发送到多个渠道
+//
+// 可在多个协程中使用
+type Multi struct {
+	mu       sync.RWMutex
+	names    []string
+	channels map[string]Notifier
+}
+
+// NewMulti 创建多渠道的通知
+func NewMulti() *Multi {
+	return &Multi{channels: make(map[string]Notifier)}
+}
+
+// Add 添加渠道。已存在同名渠道时将替换
+func (m *Multi) Add(name string, n Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.channels[name]; !ok {
+		m.names = append(m.names, name)
+	}
+	m.channels[name] = n
+}
+
+// Notify 并发地发送通知到所有渠道
+//
+// 部分渠道发送失败时，不影响其它渠道，返回记录了各渠道错误的 *MultiError
+func (m *Multi) Notify(n *Notification) error {
+	m.mu.RLock()
+	channels := make(map[string]Notifier, len(m.channels))
+	for name, c := range m.channels {
+		channels[name] = c
+	}
+	m.mu.RUnlock()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures = make(map[string]error)
+	)
+	for name, c := range channels {
+		wg.Add(1)
+		go func(name string, c Notifier) {
+			defer wg.Done()
+			if err := c.Notify(n); err != nil {
+				mu.Lock()
+				failures[name] = err
+				mu.Unlock()
+			}
+		}(name, c)
+	}
+	wg.Wait()
+
+	if len(failures) != 0 {
+		return &MultiError{Failures: failures}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package donotify
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/donething/utils-go/dotg"
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"github.com/donething/utils-go/dowx"
+	"github.com/donething/utils-go/dowx/dowxtest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMulti_Notify(t *testing.T) {
+	tgSrv := dotgtest.NewServer()
+	defer tgSrv.Close()
+	bot := dotg.NewTGBot(dotgtest.Token)
+	bot.SetAddr(tgSrv.URL)
+
+	wxSrv := dowxtest.NewServer()
+	defer wxSrv.Close()
+	qy := dowx.NewQiYe("corpid", "secret")
+	qy.SetAddr(wxSrv.URL)
+
+	var payload WebhookPayload
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		if r.Header.Get("Authorization") != "token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+	}))
+	defer hookSrv.Close()
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	n := &Notification{
+		Title:       "任务失败",
+		Body:        "第 1.5 步出错",
+		Severity:    SeverityError,
+		Link:        "https://example.com/job?id=1",
+		Attachments: []Attachment{{Path: path, Name: "日报.txt"}},
+	}
+
+	multi := NewMulti()
+	multi.Add("tg", NewTG(bot, "123"))
+	multi.Add("qiye", NewQiYe(qy, 1, ""))
+	multi.Add("webhook", NewWebhook(hookSrv.URL, map[string]string{"Authorization": "token"}))
+	multi.Add("bad", NewWebhook(hookSrv.URL, nil))
+
+	err := multi.Notify(n)
+	var e *MultiError
+	if !errors.As(err, &e) || len(e.Failures) != 1 || e.Failures["bad"] == nil {
+		t.Fatalf("应只有 bad 渠道失败，实际为 %v", err)
+	}
+
+	// TG：转义后的正文，以及附件
+	if text := tgSrv.CallsOf("sendMessage")[0].Form.Get("text"); !strings.Contains(text, `第 1\.5 步出错`) {
+		t.Errorf("TG 的正文不符：%s", text)
+	}
+	if calls := tgSrv.CallsOf("sendMediaGroup"); len(calls) != 1 || string(calls[0].Files["media0"].Content) != "report" {
+		t.Errorf("TG 的附件不符：%v", calls)
+	}
+
+	// 企业微信
+	var msg dowx.QYMsgMarkdown
+	if err = wxSrv.Messages()[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg.Markdown.Content, "[查看详情](https://example.com/job?id=1)") {
+		t.Errorf("企业微信的正文不符：%s", msg.Markdown.Content)
+	}
+	if uploads := wxSrv.Uploads(); len(uploads) != 1 || uploads[0].Filename != "日报.txt" ||
+		string(uploads[0].Content) != "report" {
+		t.Errorf("企业微信的附件不符：%+v", uploads)
+	}
+	var file dowx.QYMsgFile
+	if err = wxSrv.Messages()[1].Decode(&file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Msgtype != dowx.QYTypeFile || file.File.MediaID != wxSrv.Uploads()[0].MediaID {
+		t.Errorf("企业微信的附件消息不符：%+v", file)
+	}
+
+	// webhook
+	if payload.Severity != "错误" || payload.Attachments[0] != "日报.txt" {
+		t.Errorf("webhook 的数据不符：%+v", payload)
+	}
+}
+
+func TestQiYe_NotifyPartial(t *testing.T) {
+	srv := dowxtest.NewServer()
+	defer srv.Close()
+	srv.InvalidUsers("bad")
+	qy := dowx.NewQiYe("corpid", "secret")
+	qy.SetAddr(srv.URL)
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("report"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	n := &Notification{Title: "任务失败", Attachments: []Attachment{{Path: path}}}
+
+	// 部分接收者无效时，仍应发送附件，并返回该错误
+	err := NewQiYe(qy, 1, "good|bad").Notify(n)
+	if !dowx.IsPartialError(err) {
+		t.Errorf("应返回 *dowx.PartialError，实际为 %v", err)
+	}
+	if len(srv.Uploads()) != 1 || len(srv.Messages()) != 2 {
+		t.Errorf("应继续发送附件，实际上传 %d 个、推送 %d 条", len(srv.Uploads()), len(srv.Messages()))
+	}
+
+	// 所有接收者都无效时，不再发送附件
+	srv.InvalidUsers("good")
+	err = NewQiYe(qy, 1, "good|bad").Notify(n)
+	if err == nil || dowx.IsPartialError(err) {
+		t.Errorf("应返回推送失败的错误，实际为 %v", err)
+	}
+	if len(srv.Uploads()) != 1 {
+		t.Errorf("推送正文失败后不应发送附件，实际上传 %d 个", len(srv.Uploads()))
+	}
+}
+
+func TestFormatSandbox(t *testing.T) {
+	n := &Notification{Title: "标题", Body: "正文", Severity: SeverityWarning,
+		Attachments: []Attachment{{Path: "/tmp/a.log"}, {Path: "/tmp/b", Name: "b.txt"}}}
+
+	if title := FormatSandboxTitle(n); title != "[警告]标题" {
+		t.Errorf("标题不符：%s", title)
+	}
+	if body := FormatSandboxBody(n); body != "正文\n\n附件：a.log、b.txt" {
+		t.Errorf("正文不符：%s", body)
+	}
+}
@@ -0,0 +1,68 @@
+package donotify
+
+import (
+	"fmt"
+	"github.com/donething/utils-go/dotg"
+	"strings"
+)
+
+// TG 通过 TG 机器人发送通知
+type TG struct {
+	bot    *dotg.TGBot
+	chatID string
+}
+
+// NewTG 创建 TG 渠道
+func NewTG(bot *dotg.TGBot, chatID string) *TG {
+	return &TG{bot: bot, chatID: chatID}
+}
+
+// Notify 以 Markdown V2 文本发送通知，附件作为文件发送
+func (t *TG) Notify(n *Notification) error {
+	_, err := t.bot.SendMessage(t.chatID, FormatTG(n), nil)
+	if err != nil {
+		return err
+	}
+
+	if len(n.Attachments) == 0 {
+		return nil
+	}
+
+	// 一个媒体集最多 10 个文件
+	for start := 0; start < len(n.Attachments); start += 10 {
+		end := start + 10
+		if end > len(n.Attachments) {
+			end = len(n.Attachments)
+		}
+
+		medias := make([]*dotg.InputMedia, 0, end-start)
+		for _, a := range n.Attachments[start:end] {
+			medias = append(medias, &dotg.InputMedia{
+				Type:  dotg.TypeDocument,
+				Media: dotg.OpenPath(a.Path),
+				Name:  a.FileName(),
+			})
+		}
+		_, err = t.bot.SendMediaGroup(t.chatID, medias, nil)
+		if err != nil {
+			return fmt.Errorf("发送附件出错：%w", err)
+		}
+	}
+
+	return nil
+}
+
+// FormatTG 将通知转换为 TG 的 Markdown V2 文本
+func FormatTG(n *Notification) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s *%s*", n.Severity.Emoji(), dotg.EscapeMk(n.Title)))
+	if n.Body != "" {
+		b.WriteString("\n\n" + dotg.EscapeMk(n.Body))
+	}
+	if n.Link != "" {
+		// 链接中只需转义")"和"\"
+		link := strings.NewReplacer(`\`, `\\`, ")", `\)`).Replace(n.Link)
+		b.WriteString(fmt.Sprintf("\n\n[查看详情](%s)", link))
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package donotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/donething/utils-go/dohttp"
+	"io"
+	"net/http"
+)
+
+// WebhookPayload 通用 webhook 渠道 POST 的 JSON 数据
+type WebhookPayload struct {
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	Severity string `json:"severity"`
+	Level    int    `json:"level"` // 严重程度的数值，越大越严重
+	Link     string `json:"link,omitempty"`
+	// 附件的文件名。不上传附件的内容
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// Webhook 将通知以 JSON POST 到指定地址
+type Webhook struct {
+	url     string
+	headers map[string]string
+	client  dohttp.DoClient
+}
+
+// NewWebhook 创建通用 webhook 渠道
+//
+// headers 额外的请求头，如鉴权信息，可为 nil
+func NewWebhook(url string, headers map[string]string) *Webhook {
+	return &Webhook{url: url, headers: headers, client: dohttp.New(false, false)}
+}
+
+// Notify POST WebhookPayload。响应码不为 2xx 时返回错误
+func (w *Webhook) Notify(n *Notification) error {
+	payload := WebhookPayload{
+		Title:    n.Title,
+		Body:     n.Body,
+		Severity: n.Severity.String(),
+		Level:    int(n.Severity),
+		Link:     n.Link,
+	}
+	for i := range n.Attachments {
+		payload.Attachments = append(payload.Attachments, n.Attachments[i].FileName())
+	}
+
+	bs, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化通知出错：%w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("创建请求出错：%w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Exec(req, w.headers)
+	if err != nil {
+		return fmt.Errorf("执行请求出错：%w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("响应码为 %d：%s", resp.StatusCode, string(text))
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package donotify
+
+import (
+	"fmt"
+	"github.com/donething/utils-go/dowx"
+	"strings"
+)
+
+// QiYe 通过企业微信应用发送通知
+type QiYe struct {
+	qy      *dowx.QiYe
+	agentid int
+	users   string
+}
+
+// NewQiYe 创建企业微信渠道
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func NewQiYe(qy *dowx.QiYe, agentid int, users string) *QiYe {
+	return &QiYe{qy: qy, agentid: agentid, users: users}
+}
+
+// Notify 以 Markdown 消息发送通知，附件以 Attachment.FileName() 作为文件名，作为文件消息发送
+//
+// 部分接收者无效（*dowx.PartialError）时，其它接收者仍能收到，所以继续发送附件，最后再返回该错误
+func (q *QiYe) Notify(n *Notification) error {
+	var partial error
+	_, err := q.qy.PushMarkdown(q.agentid, FormatQiYe(n), q.users)
+	if err != nil {
+		if !dowx.IsPartialError(err) {
+			return err
+		}
+		partial = err
+	}
+
+	for _, a := range n.Attachments {
+		_, err = q.qy.PushFileAs(q.agentid, a.Path, a.FileName(), q.users)
+		if err != nil && !dowx.IsPartialError(err) {
+			return fmt.Errorf("发送附件'%s'出错：%w", a.FileName(), err)
+		}
+		if partial == nil {
+			partial = err
+		}
+	}
+
+	return partial
+}
+
+// FormatQiYe 将通知转换为企业微信的 Markdown 文本，按严重程度设置标题的颜色
+func FormatQiYe(n *Notification) string {
+	title := n.Severity.Emoji() + " " + n.Title
+	switch n.Severity {
+	case SeverityInfo:
+		title = dowx.GenMdInfoText(title)
+	default:
+		title = dowx.GenMdWarningText(title)
+	}
+
+	var b strings.Builder
+	b.WriteString("**" + title + "**")
+	if n.Body != "" {
+		b.WriteString("\n\n" + n.Body)
+	}
+	if n.Link != "" {
+		b.WriteString(fmt.Sprintf("\n\n[查看详情](%s)", n.Link))
+	}
+	return b.String()
+}
+
+// Sandbox 通过微信测试号的模板消息发送通知
+type Sandbox struct {
+	sb    *dowx.Sandbox
+	toUID string
+	tplID string
+}
+
+// NewSandbox 创建微信测试号渠道。模板需包含 title、msg、time 字段，参考 dowx.SBMsg
+func NewSandbox(sb *dowx.Sandbox, toUID string, tplID string) *Sandbox {
+	return &Sandbox{sb: sb, toUID: toUID, tplID: tplID}
+}
+
+// Notify 发送模板消息。点击消息将打开链接；不支持附件，将在正文后列出文件名
+func (s *Sandbox) Notify(n *Notification) error {
+	_, err := s.sb.PushTpl(s.toUID, s.tplID, FormatSandboxTitle(n), FormatSandboxBody(n), n.Link)
+	return err
+}
+
+// FormatSandboxTitle 生成模板消息的标题
+func FormatSandboxTitle(n *Notification) string {
+	return fmt.Sprintf("[%s]%s", n.Severity, n.Title)
+}
+
+// FormatSandboxBody 生成模板消息的正文
+func FormatSandboxBody(n *Notification) string {
+	body := n.Body
+	if len(n.Attachments) != 0 {
+		body += "\n\n附件：" + n.attachmentNames()
+	}
+	return body
+}
@@ -1,20 +1,27 @@
 package dobdpan
 
-import "io"
+import (
+	"encoding/json"
+	"io"
+)
 
 // BDFile 网盘的文件。包含上传信息
 type BDFile struct {
 	// 每个分块的 MD5，create 时需要
 	BlockMD5List []string
 
-	// 所有切片的 MD5 值组成的字符串数组
-	// 不知道什么原因，实际用于判断该文件是单个切片，还是有多个切片
-	//
-	// 由于上传大文件时，不便在 precreate 阶段获取每个切片的 md5，幸好暂时可以设置为固定值
-	// 当文件小于 4MB 时，设为 ["5910a591dd8fc18c32a8f3df4fdc1761"]，
-	// 大于时，设为 ["5910a591dd8fc18c32a8f3df4fdc1761","a5fc157d78e6ad1c7e114b056c92821e"]
+	// 所有切片的 MD5 值组成的 JSON 字符串数组，precreate 时需要
 	BlockListMd5 string
 
+	// 文件内容的 MD5、前 256KB 内容的 MD5、CRC32，秒传时需要
+	// 以上摘要都在上传前读取一遍文件后自动计算
+	ContentMD5   string
+	SliceMD5     string
+	ContentCRC32 uint32
+
+	// 上传后，是否为秒传（云端已有相同内容的文件，未实际上传）
+	Rapid bool
+
 	// 文件被保存到的远程目录。以"/"开头，如"/Pics/filename.jpg"。此值在一刻相册中无效
 	RemotePath string
 
@@ -25,6 +32,18 @@ type BDFile struct {
 	// 文件被创建时的 Unix时间戳（秒）。为 0 时 将自动设为当前 Unix 时间戳
 	LocalCtime int64
 
+	// 可选
+	// 保存上传进度的状态文件的路径。为空""时不保存，中断后需重新上传整个文件
+	StatePath string
+
+	// 可选
+	// 同时上传切片的协程数。为 0 时使用 DefaultWorkers
+	Workers int
+
+	// 可选
+	// 单个切片上传失败后的重试次数。为 0 时使用 DefaultRetries，为负数时不重试
+	Retries int
+
 	// 用于读取改文件的内容。无论改文件是 []byte、还是 File
 	Reader io.Reader
 	// 适配不同网站，手动指定的信息
@@ -34,12 +53,22 @@ type BDFile struct {
 // Req 百度网盘、一刻相册、Terabox 等不同网站的 API URL 不同，需要手动指定
 type Req struct {
 	// 上传部分的 URL
+	// RapidURL 为空""时不尝试秒传，只在 precreate 时由服务端判断
+	RapidURL     string
 	PrecreateURL string
 	SuperfileURL string
 	CreateURL    string
 
-	ListURL string
-	DelURL  string
+	// 文件管理部分的 URL。为空""时表示该网站不支持此操作
+	// 一刻相册中没有文件夹，只支持列出、按 fsid 删除文件
+	ListURL   string
+	SearchURL string
+	MetaURL   string
+	MkdirURL  string
+	// 复制、移动、重命名、按路径删除文件。需包含"opera=%s"以指定操作
+	ManageURL string
+	// 按 fsid 删除文件。需包含"%s"以指定 fsid 列表
+	DelURL string
 
 	// 请求头
 	Headers map[string]string
@@ -58,6 +87,12 @@ type PreResp struct {
 	Uploadid   string `json:"uploadid"`
 }
 
+// RapidResp 秒传的响应
+type RapidResp struct {
+	// 为 0 表示秒传成功，非 0（常见为 404）表示云端没有该文件
+	Errno int `json:"errno"`
+}
+
 // UpResp 上传分段的响应
 type UpResp struct {
 	// 为 0 时，在一刻相册中表示有错（只要有 error_code、error_msg 都为有错）；在 Terabox 中非零表示有错
@@ -93,11 +128,112 @@ type CreateResp struct {
 	} `json:"data"`
 }
 
-// FilesResp 文件列表
-type FilesResp struct {
-	Cursor string `json:"cursor"`
-	Errno  int    `json:"errno"`
-	List   []struct {
+//
+// 管理文件时的响应
+//
+
+// FileInfo 网盘中的文件、文件夹
+type FileInfo struct {
+	FSID           int64  `json:"fs_id"`
+	Path           string `json:"path"`
+	ServerFilename string `json:"server_filename"`
+	Size           int64  `json:"size"`
+	// 1 为文件夹
+	Isdir int `json:"isdir"`
+	// 1：视频；2：音频；3：图片；4：文档；5：应用；6：其它；7：种子
+	Category int `json:"category"`
+	// 文件夹没有 MD5
+	Md5         string `json:"md5"`
+	ServerCtime int64  `json:"server_ctime"`
+	ServerMtime int64  `json:"server_mtime"`
+	LocalCtime  int64  `json:"local_ctime"`
+	LocalMtime  int64  `json:"local_mtime"`
+}
+
+// IsDir 是否为文件夹
+func (f *FileInfo) IsDir() bool {
+	return f.Isdir == 1
+}
+
+// UnmarshalJSON 一刻相册中文件 ID 的键为"fsid"，而非"fs_id"
+func (f *FileInfo) UnmarshalJSON(data []byte) error {
+	type fileInfo FileInfo
+	var info struct {
+		fileInfo
 		Fsid int64 `json:"fsid"`
-	} `json:"list"`
+	}
+	err := json.Unmarshal(data, &info)
+	if err != nil {
+		return err
+	}
+
+	*f = FileInfo(info.fileInfo)
+	if f.FSID == 0 {
+		f.FSID = info.Fsid
+	}
+
+	return nil
+}
+
+// ListResp 文件列表的一页
+type ListResp struct {
+	// 不为 0 即表示有错
+	Errno int `json:"errno"`
+	// 一刻相册用于获取下一页
+	Cursor string      `json:"cursor"`
+	List   []*FileInfo `json:"list"`
+}
+
+// SearchResp 搜索结果的一页
+type SearchResp struct {
+	// 不为 0 即表示有错
+	Errno int         `json:"errno"`
+	List  []*FileInfo `json:"list"`
+	// 开放平台中为 0 表示没有更多结果。Terabox 中没有此项
+	HasMore *int `json:"has_more"`
+}
+
+// MetaResp 文件信息
+type MetaResp struct {
+	// 不为 0 即表示有错
+	Errno int         `json:"errno"`
+	Info  []*FileInfo `json:"info"`
+}
+
+// MkdirResp 创建文件夹的响应
+type MkdirResp struct {
+	// 不为 0 即表示有错
+	Errno int `json:"errno"`
+	// 创建的文件夹。与 errno 位于同一层级，由 UnmarshalJSON 解析
+	Info FileInfo `json:"-"`
+}
+
+// UnmarshalJSON 分别解析 errno 和文件夹的信息
+//
+// 不能直接嵌入 FileInfo，否则其 UnmarshalJSON 将被提升，只解析出 FileInfo 的部分
+func (r *MkdirResp) UnmarshalJSON(data []byte) error {
+	var e struct {
+		Errno int `json:"errno"`
+	}
+	err := json.Unmarshal(data, &e)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, &r.Info)
+	if err != nil {
+		return err
+	}
+
+	r.Errno = e.Errno
+	return nil
+}
+
+// ManageResp 复制、移动、重命名、删除文件的响应
+type ManageResp struct {
+	// 不为 0 即表示有错。部分文件失败时为 12，各文件的结果在 Info 中
+	Errno int `json:"errno"`
+	Info  []struct {
+		Errno int    `json:"errno"`
+		Path  string `json:"path"`
+	} `json:"info"`
 }
@@ -0,0 +1,343 @@
+package dobdpan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupported 该网站不支持此操作，即 Req 中对应的 URL 为空""
+	ErrUnsupported = errors.New("该网站不支持此操作")
+)
+
+const (
+	// 列出、搜索文件时每页的数量
+	pageNum = 100
+	// 搜索时最多请求的页数。避免服务端忽略页码、始终返回同一页时无限循环
+	maxSearchPages = 100
+)
+
+// DelAll 删除每页后，随机等待 1-5 倍的该时长
+var delInterval = time.Second
+
+// 为 URL 添加查询参数
+func withQuery(u string, query url.Values) string {
+	if strings.Contains(u, "?") {
+		return u + "&" + query.Encode()
+	}
+	return u + "?" + query.Encode()
+}
+
+// 发送请求，并将响应解析到 result。form 为 nil 时发送 GET 请求，否则 POST 表单
+//
+// result 需包含 Errno 字段，不为 0 时返回错误
+func (r *Req) call(u string, form url.Values, result interface{}) error {
+	var bs []byte
+	var err error
+	if form == nil {
+		bs, err = client.GetBytes(u, r.Headers)
+	} else {
+		bs, err = client.PostForm(u, form.Encode(), r.Headers)
+	}
+	if err != nil {
+		return fmt.Errorf("执行请求出错：%w", err)
+	}
+
+	var e struct {
+		Errno int `json:"errno"`
+	}
+	err = json.Unmarshal(bs, &e)
+	if err != nil {
+		return fmt.Errorf("解析响应出错：%w ==> %s", err, string(bs))
+	}
+	if e.Errno != 0 {
+		return fmt.Errorf("响应表示失败：%s", string(bs))
+	}
+
+	err = json.Unmarshal(bs, result)
+	if err != nil {
+		return fmt.Errorf("解析响应出错：%w ==> %s", err, string(bs))
+	}
+
+	return nil
+}
+
+// List 列出文件夹下的一页文件
+//
+// cursor 为空""时列出第一页，之后传递上次返回的 next；next 为空""时表示已列完。一刻相册中没有文件夹，dir 无效
+func (r *Req) List(dir string, cursor string) (files []*FileInfo, next string, err error) {
+	if r.ListURL == "" {
+		return nil, "", ErrUnsupported
+	}
+
+	// Terabox 按页码翻页，开放平台按起始位置翻页，一刻相册按服务端返回的 cursor 翻页
+	page, err := strconv.Atoi(cursor)
+	if err != nil {
+		page = 1
+	}
+	query := url.Values{
+		"dir":    []string{dir},
+		"page":   []string{strconv.Itoa(page)},
+		"num":    []string{strconv.Itoa(pageNum)},
+		"start":  []string{strconv.Itoa((page - 1) * pageNum)},
+		"limit":  []string{strconv.Itoa(pageNum)},
+		"cursor": []string{cursor},
+	}
+
+	var resp ListResp
+	err = r.call(withQuery(r.ListURL, query), nil, &resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("列出文件出错：%w", err)
+	}
+
+	// 列完后将返回空列表
+	if len(resp.List) == 0 {
+		return nil, "", nil
+	}
+	if resp.Cursor != "" {
+		return resp.List, resp.Cursor, nil
+	}
+	return resp.List, strconv.Itoa(page + 1), nil
+}
+
+// Walk 递归遍历文件夹下的所有文件、文件夹，对每项调用 fn
+//
+// fn 对文件夹返回 fs.SkipDir 时，不遍历该文件夹；返回其它错误时，停止遍历并返回该错误
+func (r *Req) Walk(dir string, fn func(f *FileInfo) error) error {
+	cursor := ""
+	for {
+		files, next, err := r.List(dir, cursor)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			err = fn(f)
+			if f.IsDir() && errors.Is(err, fs.SkipDir) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if f.IsDir() {
+				err = r.Walk(f.Path, fn)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Search 在文件夹中按文件名搜索
+//
+// recursive 是否搜索子文件夹
+func (r *Req) Search(key string, dir string, recursive bool) ([]*FileInfo, error) {
+	if r.SearchURL == "" {
+		return nil, ErrUnsupported
+	}
+
+	recursion := "0"
+	if recursive {
+		recursion = "1"
+	}
+
+	result := make([]*FileInfo, 0)
+	for page := 1; page <= maxSearchPages; page++ {
+		query := url.Values{
+			"key":       []string{key},
+			"dir":       []string{dir},
+			"recursion": []string{recursion},
+			"page":      []string{strconv.Itoa(page)},
+			"num":       []string{strconv.Itoa(pageNum)},
+		}
+
+		var resp SearchResp
+		err := r.call(withQuery(r.SearchURL, query), nil, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("搜索文件出错：%w", err)
+		}
+		result = append(result, resp.List...)
+
+		// 不足一页，或服务端表示没有更多结果时，已搜索完
+		if len(resp.List) < pageNum || (resp.HasMore != nil && *resp.HasMore == 0) {
+			return result, nil
+		}
+	}
+
+	return result, nil
+}
+
+// Meta 获取文件的信息
+func (r *Req) Meta(paths ...string) ([]*FileInfo, error) {
+	if r.MetaURL == "" {
+		return nil, ErrUnsupported
+	}
+
+	bs, err := json.Marshal(paths)
+	if err != nil {
+		return nil, fmt.Errorf("序列化文件路径出错：%w", err)
+	}
+
+	var resp MetaResp
+	err = r.call(withQuery(r.MetaURL, url.Values{"target": []string{string(bs)}}), nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件信息出错：%w", err)
+	}
+
+	return resp.Info, nil
+}
+
+// Mkdir 创建文件夹。上级文件夹不存在时将一起创建
+func (r *Req) Mkdir(dir string) (*FileInfo, error) {
+	if r.MkdirURL == "" {
+		return nil, ErrUnsupported
+	}
+
+	form := url.Values{}
+	form.Add("path", dir)
+	form.Add("isdir", "1")
+	form.Add("block_list", "[]")
+	// rtype 的值：0 为同名时返回错误
+	form.Add("rtype", "0")
+
+	var resp MkdirResp
+	err := r.call(r.MkdirURL, form, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("创建文件夹出错：%w", err)
+	}
+
+	return &resp.Info, nil
+}
+
+// Copy 复制文件到文件夹 destDir。newName 为空""时使用原文件名
+func (r *Req) Copy(src string, destDir string, newName string) error {
+	return r.manage("copy", []interface{}{moveItem(src, destDir, newName)})
+}
+
+// Move 移动文件到文件夹 destDir。newName 为空""时使用原文件名
+func (r *Req) Move(src string, destDir string, newName string) error {
+	return r.manage("move", []interface{}{moveItem(src, destDir, newName)})
+}
+
+// Rename 重命名文件
+func (r *Req) Rename(src string, newName string) error {
+	return r.manage("rename", []interface{}{map[string]string{"path": src, "newname": newName}})
+}
+
+// Delete 按路径删除文件、文件夹
+func (r *Req) Delete(paths ...string) error {
+	list := make([]interface{}, len(paths))
+	for i, p := range paths {
+		list[i] = p
+	}
+	return r.manage("delete", list)
+}
+
+// DeleteByID 按 fsid 删除文件
+func (r *Req) DeleteByID(fsids ...int64) error {
+	if r.DelURL == "" {
+		return ErrUnsupported
+	}
+
+	bs, err := json.Marshal(fsids)
+	if err != nil {
+		return fmt.Errorf("序列化文件的 ID 列表时出错：%w", err)
+	}
+
+	var resp ManageResp
+	err = r.call(fmt.Sprintf(r.DelURL, url.QueryEscape(string(bs))), nil, &resp)
+	if err != nil {
+		return fmt.Errorf("删除文件出错：%w", err)
+	}
+
+	return nil
+}
+
+// 移动、复制时的文件项
+func moveItem(src string, destDir string, newName string) map[string]string {
+	if newName == "" {
+		newName = path.Base(src)
+	}
+	return map[string]string{"path": src, "dest": destDir, "newname": newName}
+}
+
+// 执行复制、移动、重命名、删除操作
+func (r *Req) manage(opera string, list []interface{}) error {
+	if r.ManageURL == "" {
+		return ErrUnsupported
+	}
+
+	bs, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("序列化文件列表出错：%w", err)
+	}
+
+	form := url.Values{}
+	form.Add("filelist", string(bs))
+	// 目标已存在同名文件时返回错误
+	form.Add("ondup", "fail")
+
+	var resp ManageResp
+	err = r.call(fmt.Sprintf(r.ManageURL, opera), form, &resp)
+	if err != nil {
+		return fmt.Errorf("执行操作 %s 出错：%w", opera, err)
+	}
+
+	return nil
+}
+
+// DelAll 删除所有文件
+func DelAll(req *Req) error {
+	rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for {
+		// 列出文件。删除后，剩下的文件会成为第一页
+		files, _, err := req.List("/", "")
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			break
+		}
+
+		// 删除文件。不支持按 fsid 删除时（如 Terabox、开放平台），按路径删除
+		if req.DelURL == "" {
+			paths := make([]string, len(files))
+			for i, f := range files {
+				paths[i] = f.Path
+			}
+			err = req.Delete(paths...)
+		} else {
+			fidList := make([]int64, len(files))
+			for i, f := range files {
+				fidList[i] = f.FSID
+			}
+			err = req.DeleteByID(fidList...)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("已删除该页图片，将继续删除下页\n")
+
+		r := rand.Intn(5)
+		time.Sleep(time.Duration(r+1) * delInterval)
+	}
+
+	return nil
+}
@@ -0,0 +1,430 @@
+package dobdpan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 模拟 Terabox 的文件管理接口，每页只返回 2 项，以测试翻页
+type fakeDisk struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	files  map[string]*FileInfo
+	nextID int64
+}
+
+func newFakeDisk(t *testing.T) *fakeDisk {
+	d := &fakeDisk{files: make(map[string]*FileInfo)}
+
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		bs, _ := json.Marshal(v)
+		w.Write(bs)
+	}
+	// 每页 size 项时，第 page 页的项
+	pageOf := func(list []*FileInfo, page int, size int) []*FileInfo {
+		sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+		start := (page - 1) * size
+		if start >= len(list) {
+			return []*FileInfo{}
+		}
+		end := start + size
+		if end > len(list) {
+			end = len(list)
+		}
+		return list[start:end]
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/list", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		q := r.URL.Query()
+		page, _ := strconv.Atoi(q.Get("page"))
+		list := make([]*FileInfo, 0)
+		for _, f := range d.files {
+			if path.Dir(f.Path) == q.Get("dir") {
+				list = append(list, f)
+			}
+		}
+		writeJSON(w, map[string]interface{}{"errno": 0, "list": pageOf(list, page, 2)})
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		q := r.URL.Query()
+		page, _ := strconv.Atoi(q.Get("page"))
+		list := make([]*FileInfo, 0)
+		for _, f := range d.files {
+			inDir := path.Dir(f.Path) == q.Get("dir")
+			if q.Get("recursion") == "1" {
+				inDir = strings.HasPrefix(f.Path, strings.TrimSuffix(q.Get("dir"), "/")+"/")
+			}
+			if inDir && strings.Contains(f.ServerFilename, q.Get("key")) {
+				list = append(list, f)
+			}
+		}
+		num, _ := strconv.Atoi(q.Get("num"))
+		writeJSON(w, map[string]interface{}{"errno": 0, "list": pageOf(list, page, num)})
+	})
+	// 忽略页码，始终返回满的一页；hasMore 不为空""时返回 has_more
+	fullPage := func(hasMore string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			list := make([]*FileInfo, pageNum)
+			for i := range list {
+				list[i] = &FileInfo{FSID: int64(i + 1), Path: fmt.Sprintf("/%d.txt", i)}
+			}
+			resp := map[string]interface{}{"errno": 0, "list": list}
+			if hasMore != "" {
+				resp["has_more"], _ = strconv.Atoi(hasMore)
+			}
+			writeJSON(w, resp)
+		}
+	}
+	mux.HandleFunc("/api/search-stuck", fullPage(""))
+	mux.HandleFunc("/api/search-last", fullPage("0"))
+	mux.HandleFunc("/api/filemetas", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		var paths []string
+		json.Unmarshal([]byte(r.URL.Query().Get("target")), &paths)
+		info := make([]*FileInfo, 0)
+		for _, p := range paths {
+			f, ok := d.files[p]
+			if !ok {
+				writeJSON(w, map[string]interface{}{"errno": 12})
+				return
+			}
+			info = append(info, f)
+		}
+		writeJSON(w, map[string]interface{}{"errno": 0, "info": info})
+	})
+	mux.HandleFunc("/api/create", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		if r.FormValue("isdir") != "1" {
+			t.Errorf("应创建文件夹")
+		}
+		p := r.FormValue("path")
+		if _, ok := d.files[p]; ok {
+			writeJSON(w, map[string]interface{}{"errno": -8})
+			return
+		}
+		f := d.add(p, true)
+		writeJSON(w, map[string]interface{}{"errno": 0, "fs_id": f.FSID, "path": f.Path, "isdir": 1})
+	})
+	mux.HandleFunc("/api/filemanager", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		opera := r.URL.Query().Get("opera")
+		if opera == "delete" {
+			var paths []string
+			json.Unmarshal([]byte(r.FormValue("filelist")), &paths)
+			for _, p := range paths {
+				d.remove(p)
+			}
+			writeJSON(w, map[string]interface{}{"errno": 0})
+			return
+		}
+
+		var items []map[string]string
+		json.Unmarshal([]byte(r.FormValue("filelist")), &items)
+		for _, item := range items {
+			src := d.files[item["path"]]
+			if src == nil {
+				writeJSON(w, map[string]interface{}{"errno": 12,
+					"info": []map[string]interface{}{{"errno": -9, "path": item["path"]}}})
+				return
+			}
+
+			dest := item["dest"]
+			if opera == "rename" {
+				dest = path.Dir(item["path"])
+			}
+			d.add(path.Join(dest, item["newname"]), src.IsDir())
+			if opera != "copy" {
+				d.remove(item["path"])
+			}
+		}
+		writeJSON(w, map[string]interface{}{"errno": 0})
+	})
+
+	// 一刻相册：按 cursor 翻页，键为"fsid"
+	mux.HandleFunc("/yike/list", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		page, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Query().Get("cursor"), "c"))
+		list := make([]*FileInfo, 0)
+		for _, f := range d.files {
+			list = append(list, f)
+		}
+		items := make([]map[string]interface{}, 0)
+		for _, f := range pageOf(list, page+1, 2) {
+			items = append(items, map[string]interface{}{"fsid": f.FSID, "path": f.Path})
+		}
+		writeJSON(w, map[string]interface{}{"errno": 0, "cursor": fmt.Sprintf("c%d", page+1), "list": items})
+	})
+	mux.HandleFunc("/yike/delete", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		var ids []int64
+		json.Unmarshal([]byte(r.URL.Query().Get("fsid_list")), &ids)
+		for _, id := range ids {
+			for p, f := range d.files {
+				if f.FSID == id {
+					delete(d.files, p)
+				}
+			}
+		}
+		writeJSON(w, map[string]interface{}{"errno": 0})
+	})
+
+	d.Server = httptest.NewServer(mux)
+	t.Cleanup(d.Close)
+	return d
+}
+
+// 添加文件，同时创建上级文件夹。需已持有锁
+func (d *fakeDisk) add(p string, isDir bool) *FileInfo {
+	if dir := path.Dir(p); dir != "/" {
+		if _, ok := d.files[dir]; !ok {
+			d.add(dir, true)
+		}
+	}
+
+	d.nextID++
+	f := &FileInfo{FSID: d.nextID, Path: p, ServerFilename: path.Base(p)}
+	if isDir {
+		f.Isdir = 1
+	}
+	d.files[p] = f
+	return f
+}
+
+// 删除文件及其子文件。需已持有锁
+func (d *fakeDisk) remove(p string) {
+	for fp := range d.files {
+		if fp == p || strings.HasPrefix(fp, p+"/") {
+			delete(d.files, fp)
+		}
+	}
+}
+
+func (d *fakeDisk) req() *Req {
+	return &Req{
+		ListURL:   d.URL + "/api/list?order=name",
+		SearchURL: d.URL + "/api/search",
+		MetaURL:   d.URL + "/api/filemetas",
+		MkdirURL:  d.URL + "/api/create?a=commit",
+		ManageURL: d.URL + "/api/filemanager?async=0&opera=%s",
+	}
+}
+
+func TestReq_Walk(t *testing.T) {
+	d := newFakeDisk(t)
+	for _, p := range []string{"/a/1.txt", "/a/2.txt", "/a/3.txt", "/a/b/4.txt", "/c.txt"} {
+		d.add(p, false)
+	}
+	req := d.req()
+
+	// 遍历所有文件，跨越多页
+	var paths []string
+	err := req.Walk("/", func(f *FileInfo) error {
+		paths = append(paths, f.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/a", "/a/1.txt", "/a/2.txt", "/a/3.txt", "/a/b", "/a/b/4.txt", "/c.txt"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("遍历结果不符：%v", paths)
+	}
+
+	// 跳过文件夹
+	paths = nil
+	err = req.Walk("/", func(f *FileInfo) error {
+		paths = append(paths, f.Path)
+		if f.Path == "/a" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(paths, []string{"/a", "/c.txt"}) {
+		t.Errorf("跳过文件夹后的遍历结果不符：%v", paths)
+	}
+
+	// 搜索
+	files, err := req.Search(".txt", "/a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Errorf("递归搜索应找到 4 个文件，实际为 %d 个", len(files))
+	}
+	files, err = req.Search(".txt", "/a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("不递归搜索应找到 3 个文件，实际为 %d 个", len(files))
+	}
+
+	// 服务端表示没有更多结果时停止
+	req.SearchURL = d.URL + "/api/search-last"
+	files, err = req.Search(".txt", "/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != pageNum {
+		t.Errorf("has_more 为 0 时应只搜索 1 页，实际得到 %d 项", len(files))
+	}
+
+	// 服务端忽略页码时，不会无限循环
+	req.SearchURL = d.URL + "/api/search-stuck"
+	files, err = req.Search(".txt", "/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != maxSearchPages*pageNum {
+		t.Errorf("最多应搜索 %d 页，实际得到 %d 项", maxSearchPages, len(files))
+	}
+}
+
+func TestDelAll_ByPath(t *testing.T) {
+	delInterval = 0
+	d := newFakeDisk(t)
+	for _, p := range []string{"/a/1.txt", "/a/b/2.txt", "/c.txt", "/d.txt", "/e.txt"} {
+		d.add(p, false)
+	}
+
+	// Terabox 不支持按 fsid 删除，改为按路径删除
+	if err := DelAll(d.req()); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.files) != 0 {
+		t.Errorf("应删除所有文件，还剩 %d 项", len(d.files))
+	}
+}
+
+func TestReq_Manage(t *testing.T) {
+	d := newFakeDisk(t)
+	d.add("/c.txt", false)
+	req := d.req()
+
+	dir, err := req.Mkdir("/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dir.IsDir() || dir.Path != "/a/b" {
+		t.Errorf("创建的文件夹不符：%+v", dir)
+	}
+	if _, err = req.Mkdir("/a/b"); err == nil {
+		t.Errorf("文件夹已存在时应返回错误")
+	}
+
+	if err = req.Rename("/c.txt", "d.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err = req.Move("/d.txt", "/a", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = req.Copy("/a/d.txt", "/a/b", "e.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err = req.Move("/none.txt", "/a", ""); err == nil {
+		t.Errorf("文件不存在时应返回错误")
+	}
+
+	files, err := req.Meta("/a/d.txt", "/a/b/e.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].ServerFilename != "d.txt" || files[1].ServerFilename != "e.txt" {
+		t.Errorf("文件信息不符：%+v", files)
+	}
+
+	// 删除文件夹时，其中的文件一起删除
+	if err = req.Delete("/a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = req.Meta("/a/b/e.txt"); err == nil {
+		t.Errorf("文件应已被删除")
+	}
+
+	// Terabox 不支持按 fsid 删除
+	if err = req.DeleteByID(1); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("应返回 ErrUnsupported，实际为 %v", err)
+	}
+}
+
+func TestMkdirResp_Unmarshal(t *testing.T) {
+	var resp MkdirResp
+	err := json.Unmarshal([]byte(`{"errno":-8,"fs_id":12,"path":"/a","isdir":1}`), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Errno != -8 || resp.Info.FSID != 12 || resp.Info.Path != "/a" || !resp.Info.IsDir() {
+		t.Errorf("解析的响应不符：%+v", resp)
+	}
+}
+
+func TestReq_Yike(t *testing.T) {
+	d := newFakeDisk(t)
+	for i := 0; i < 5; i++ {
+		d.add(fmt.Sprintf("/%d.jpg", i), false)
+	}
+	req := &Req{
+		ListURL: d.URL + "/yike/list?clienttype=70",
+		DelURL:  d.URL + "/yike/delete?clienttype=70&fsid_list=%s",
+	}
+
+	// 按 cursor 翻页，并解析"fsid"
+	ids := make([]int64, 0)
+	err := req.Walk("/", func(f *FileInfo) error {
+		ids = append(ids, f.FSID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5}) {
+		t.Errorf("文件的 ID 不符：%v", ids)
+	}
+
+	if err = req.DeleteByID(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	files, _, err := req.List("/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].FSID != 3 {
+		t.Errorf("删除后的文件列表不符：%+v", files)
+	}
+
+	if _, err = req.Mkdir("/a"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("一刻相册应不支持创建文件夹，实际为 %v", err)
+	}
+}
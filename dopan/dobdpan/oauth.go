@@ -0,0 +1,295 @@
+package dobdpan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	urlDeviceCode = "/oauth/2.0/device/code"
+	urlToken      = "/oauth/2.0/token"
+
+	// access_token 在过期前多久刷新
+	tokenMargin = 5 * time.Minute
+)
+
+var (
+	// ErrNotAuthorized 还未授权，或保存的 token 已丢失，需调用 Authorize 重新授权
+	ErrNotAuthorized = errors.New("未授权")
+	// ErrDeviceExpired 用户未在设备码有效期内完成授权
+	ErrDeviceExpired = errors.New("设备码已过期")
+)
+
+// 轮询授权结果时的时间单位。服务端返回的 interval、expires_in 以秒为单位
+var pollUnit = time.Second
+
+// Token 开放平台的授权
+type Token struct {
+	AccessToken string `json:"access_token"`
+	// 每次刷新 access_token 后，refresh_token 也会改变，旧的将失效，需保存新的
+	RefreshToken string `json:"refresh_token"`
+	// access_token 过期时的 Unix 时间戳（秒）
+	Expires int64  `json:"expires"`
+	Scope   string `json:"scope"`
+}
+
+// TokenStore 保存授权，以便程序重启后不必重新授权
+type TokenStore interface {
+	// Load 读取授权。还未保存过时，返回 nil
+	Load() (*Token, error)
+	// Save 保存授权
+	Save(token *Token) error
+}
+
+// FileTokenStore 在 JSON 文件中保存授权
+type FileTokenStore struct {
+	path string
+}
+
+// NewFileTokenStore 创建保存在 path 的授权。文件不存在时将在保存时创建
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load 读取授权
+func (s *FileTokenStore) Load() (*Token, error) {
+	bs, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取授权文件出错：%w", err)
+	}
+
+	var token Token
+	err = json.Unmarshal(bs, &token)
+	if err != nil {
+		return nil, fmt.Errorf("解析授权文件出错：%w", err)
+	}
+
+	return &token, nil
+}
+
+// Save 保存授权
+func (s *FileTokenStore) Save(token *Token) error {
+	bs, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化授权出错：%w", err)
+	}
+
+	err = writeFile(s.path, bs)
+	if err != nil {
+		return fmt.Errorf("保存授权文件出错：%w", err)
+	}
+
+	return nil
+}
+
+// DeviceCode 设备码模式的授权信息。需让用户访问 VerificationURL 并输入 UserCode，或扫描 QrcodeURL 的二维码
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	QrcodeURL       string `json:"qrcode_url"`
+	// 设备码的有效期（秒）
+	ExpiresIn int `json:"expires_in"`
+	// 轮询授权结果的间隔（秒）
+	Interval int `json:"interval"`
+}
+
+// 获取、刷新 token 的响应
+type tokenResp struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope"`
+
+	// 出错时不为空""
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Authorize 以设备码模式授权
+//
+// show 展示授权信息，引导用户完成授权，如打印 VerificationURL 和 UserCode。之后将等待用户授权，授权后保存 token
+func (c *Client) Authorize(show func(code *DeviceCode)) error {
+	code, err := c.DeviceCode()
+	if err != nil {
+		return err
+	}
+
+	show(code)
+
+	_, err = c.WaitDeviceToken(code)
+	return err
+}
+
+// DeviceCode 获取设备码
+func (c *Client) DeviceCode() (*DeviceCode, error) {
+	query := url.Values{
+		"response_type": []string{"device_code"},
+		"client_id":     []string{c.appKey},
+		"scope":         []string{"basic,netdisk"},
+	}
+	bs, err := client.GetBytes(c.oauthAddr+urlDeviceCode+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取设备码出错：%w", err)
+	}
+
+	var code struct {
+		DeviceCode
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	err = json.Unmarshal(bs, &code)
+	if err != nil {
+		return nil, fmt.Errorf("解析设备码出错：%w ==> %s", err, string(bs))
+	}
+	if code.Error != "" || code.DeviceCode.DeviceCode == "" {
+		return nil, fmt.Errorf("获取设备码失败：%s", string(bs))
+	}
+
+	return &code.DeviceCode, nil
+}
+
+// WaitDeviceToken 轮询设备码的授权结果，直到用户完成授权，或设备码过期
+//
+// 授权后将保存 token
+func (c *Client) WaitDeviceToken(code *DeviceCode) (*Token, error) {
+	interval := code.Interval
+	if interval <= 0 {
+		interval = 5
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * pollUnit)
+
+	query := url.Values{
+		"grant_type":    []string{"device_token"},
+		"code":          []string{code.DeviceCode},
+		"client_id":     []string{c.appKey},
+		"client_secret": []string{c.secretKey},
+	}
+	for {
+		time.Sleep(time.Duration(interval) * pollUnit)
+		if time.Now().After(deadline) {
+			return nil, ErrDeviceExpired
+		}
+
+		resp, err := c.requestToken(query)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.Error {
+		case "":
+			return c.setToken(resp)
+		// 用户还未授权
+		case "authorization_pending":
+			continue
+		// 轮询过快
+		case "slow_down":
+			interval += 5
+			continue
+		case "expired_token":
+			return nil, ErrDeviceExpired
+		default:
+			return nil, fmt.Errorf("获取授权失败：%s %s", resp.Error, resp.ErrorDescription)
+		}
+	}
+}
+
+// 获取可用的 access_token。即将过期，或与 stale 相同时，刷新后返回
+//
+// stale 调用 API 时被提示已失效的 access_token，为空""时只判断是否过期。
+// 多个协程同时发现失效时，只有第一个会刷新，其它的直接使用刷新后的
+func (c *Client) accessToken(stale string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == nil && c.store != nil {
+		token, err := c.store.Load()
+		if err != nil {
+			return "", err
+		}
+		c.token = token
+	}
+	if c.token == nil {
+		return "", ErrNotAuthorized
+	}
+
+	expired := time.Now().Add(tokenMargin).Unix() >= c.token.Expires
+	if expired || (stale != "" && stale == c.token.AccessToken) {
+		err := c.refresh()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return c.token.AccessToken, nil
+}
+
+// 用 refresh_token 刷新 access_token。需已持有锁
+func (c *Client) refresh() error {
+	query := url.Values{
+		"grant_type":    []string{"refresh_token"},
+		"refresh_token": []string{c.token.RefreshToken},
+		"client_id":     []string{c.appKey},
+		"client_secret": []string{c.secretKey},
+	}
+	resp, err := c.requestToken(query)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("刷新授权失败(%w)：%s %s", ErrNotAuthorized, resp.Error, resp.ErrorDescription)
+	}
+
+	_, err = c.saveToken(resp)
+	return err
+}
+
+// 加锁后保存获取到的 token
+func (c *Client) setToken(resp *tokenResp) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.saveToken(resp)
+}
+
+// 保存获取到的 token。需已持有锁
+func (c *Client) saveToken(resp *tokenResp) (*Token, error) {
+	token := &Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		Expires:      time.Now().Unix() + resp.ExpiresIn,
+		Scope:        resp.Scope,
+	}
+	if c.store != nil {
+		err := c.store.Save(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.token = token
+
+	return token, nil
+}
+
+// 请求 token 接口
+func (c *Client) requestToken(query url.Values) (*tokenResp, error) {
+	bs, err := client.GetBytes(c.oauthAddr+urlToken+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求授权出错：%w", err)
+	}
+
+	var resp tokenResp
+	err = json.Unmarshal(bs, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("解析授权出错：%w ==> %s", err, string(bs))
+	}
+
+	return &resp, nil
+}
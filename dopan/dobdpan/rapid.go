@@ -0,0 +1,125 @@
+package dobdpan
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/url"
+)
+
+var (
+	// ErrNotSeekable 计算摘要需要先读取一遍文件，上传时再从头读取，Reader 需实现 io.Seeker
+	ErrNotSeekable = errors.New("Reader 未实现 io.Seeker")
+)
+
+// 读取一遍文件，计算秒传需要的全文 MD5、前 256KB 的 MD5、CRC32，以及每个切片的 MD5
+//
+// 读取完后回到读取前的位置，以便上传
+func (f *BDFile) digest() error {
+	seeker, ok := f.Reader.(io.Seeker)
+	if !ok {
+		return ErrNotSeekable
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("获取读取位置出错：%w", err)
+	}
+
+	full := md5.New()
+	crc := crc32.NewIEEE()
+	blocks := make([]string, 0, (f.Size+splitSize-1)/splitSize)
+	sliceMD5 := fmt.Sprintf("%x", md5.Sum(nil))
+
+	var size int64
+	bs := make([]byte, splitSize)
+	for {
+		n, err := io.ReadFull(f.Reader, bs)
+		// 已读完
+		if err == io.EOF {
+			break
+		}
+		// 读取出错。最后一个切片不足 splitSize 时为 io.ErrUnexpectedEOF
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("计算摘要时读取文件出错：%w", err)
+		}
+
+		// 前 256KB 都在第一个切片中
+		if size == 0 {
+			sliceMD5 = fmt.Sprintf("%x", md5.Sum(bs[:minInt(n, md5Size)]))
+		}
+
+		full.Write(bs[:n])
+		crc.Write(bs[:n])
+		blocks = append(blocks, fmt.Sprintf("%x", md5.Sum(bs[:n])))
+		size += int64(n)
+
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+
+	if size != f.Size {
+		return fmt.Errorf("读取到 %d 字节，与文件的大小 %d 不符", size, f.Size)
+	}
+
+	_, err = seeker.Seek(start, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("回到读取位置出错：%w", err)
+	}
+
+	bsBlocks, err := json.Marshal(blocks)
+	if err != nil {
+		return fmt.Errorf("序列化切片的 MD5 出错：%w", err)
+	}
+
+	f.ContentMD5 = fmt.Sprintf("%x", full.Sum(nil))
+	f.SliceMD5 = sliceMD5
+	f.ContentCRC32 = crc.Sum32()
+	f.BlockMD5List = blocks
+	f.BlockListMd5 = string(bsBlocks)
+
+	return nil
+}
+
+// 尝试秒传。云端已有相同内容的文件时返回 true
+//
+// 网站不支持（Req.RapidURL 为空""）、云端没有该文件时，返回 false，需正常上传
+func (f *BDFile) rapidupload() (bool, error) {
+	if f.Req.RapidURL == "" {
+		return false, nil
+	}
+
+	form := url.Values{}
+	form.Add("path", f.RemotePath)
+	form.Add("content-length", fmt.Sprintf("%d", f.Size))
+	form.Add("content-md5", f.ContentMD5)
+	form.Add("slice-md5", f.SliceMD5)
+	form.Add("content-crc32", fmt.Sprintf("%d", f.ContentCRC32))
+	form.Add("local_ctime", fmt.Sprintf("%d", f.LocalCtime))
+	// rtype 的值：1 为重命名同目录、同名文件
+	form.Add("rtype", "1")
+
+	bs, err := client.PostForm(f.Req.RapidURL, form.Encode(), f.Req.Headers)
+	if err != nil {
+		return false, fmt.Errorf("秒传出错：%w", err)
+	}
+
+	var resp RapidResp
+	err = json.Unmarshal(bs, &resp)
+	if err != nil {
+		return false, fmt.Errorf("解析秒传的响应出错：%w ==> %s", err, string(bs))
+	}
+
+	// 非 0 时（常见为 404），表示云端没有该文件
+	return resp.Errno == 0, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
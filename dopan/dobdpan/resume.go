@@ -0,0 +1,118 @@
+package dobdpan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StateMaxAge 状态文件中 uploadid 的有效期。超过后将丢弃该进度，重新预创建
+//
+// 官方未说明 uploadid 的有效期，保守地设为 1 天
+var StateMaxAge = 24 * time.Hour
+
+// 上传进度。保存在状态文件中，用于中断后续传
+type uploadState struct {
+	// 用于判断状态文件是否属于当前文件
+	RemotePath string `json:"remote_path"`
+	Size       int64  `json:"size"`
+	ContentMD5 string `json:"content_md5"`
+
+	Uploadid string `json:"uploadid"`
+	// 已上传的切片的 MD5，键为切片的序号
+	Blocks map[int]string `json:"blocks"`
+	// 获取 uploadid 的 Unix 时间戳（秒）
+	Created int64 `json:"created"`
+}
+
+// 读取状态文件中的上传进度
+//
+// 需先计算文件的摘要。未设置状态文件、文件不存在、不属于当前文件（路径、大小、内容不同）、已过期时，
+// 返回 nil，需重新上传整个文件
+func (f *BDFile) loadState() (*uploadState, error) {
+	if f.StatePath == "" {
+		return nil, nil
+	}
+
+	bs, err := os.ReadFile(f.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取状态文件出错：%w", err)
+	}
+
+	var state uploadState
+	err = json.Unmarshal(bs, &state)
+	if err != nil {
+		return nil, fmt.Errorf("解析状态文件出错：%w", err)
+	}
+
+	if state.RemotePath != f.RemotePath || state.Size != f.Size ||
+		state.ContentMD5 != f.ContentMD5 || state.Uploadid == "" {
+		return nil, nil
+	}
+	if time.Since(time.Unix(state.Created, 0)) > StateMaxAge {
+		return nil, nil
+	}
+	if state.Blocks == nil {
+		state.Blocks = make(map[int]string)
+	}
+
+	return &state, nil
+}
+
+// 保存上传进度到状态文件
+func (f *BDFile) saveState(state *uploadState) error {
+	if f.StatePath == "" {
+		return nil
+	}
+
+	bs, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("序列化上传进度出错：%w", err)
+	}
+
+	err = writeFile(f.StatePath, bs)
+	if err != nil {
+		return fmt.Errorf("保存状态文件出错：%w", err)
+	}
+
+	return nil
+}
+
+// 写入文件。先写临时文件再重命名，中断时不会留下写了一半的内容
+func writeFile(path string, bs []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件出错：%w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(bs)
+	if errC := tmp.Close(); err == nil {
+		err = errC
+	}
+	if err != nil {
+		return fmt.Errorf("写入临时文件出错：%w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// 上传完成后删除状态文件
+func (f *BDFile) removeState() error {
+	if f.StatePath == "" {
+		return nil
+	}
+
+	err := os.Remove(f.StatePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("删除状态文件出错：%w", err)
+	}
+
+	return nil
+}
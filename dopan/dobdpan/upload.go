@@ -1,4 +1,6 @@
 // Package dobdpan 上传文件到百度网盘
+//
+// 通过 Req 指定一刻相册、Terabox 等网站的接口，使用 Cookie 上传；或者通过 Client 使用开放平台的官方 API
 // @see https://pan.baidu.com/union/document/basic#%E9%A2%84%E4%B8%8A%E4%BC%A0
 package dobdpan
 
@@ -8,9 +10,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/donething/utils-go/dohttp"
-	"math/rand"
+	"io"
 	"net/url"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -22,10 +25,15 @@ const (
 )
 
 const (
-	tagOneSeq  = `["5910a591dd8fc18c32a8f3df4fdc1761"]`
-	tagMoreSeq = `["5910a591dd8fc18c32a8f3df4fdc1761","a5fc157d78e6ad1c7e114b056c92821e"]`
+	// DefaultWorkers 默认同时上传切片的协程数
+	DefaultWorkers = 3
+	// DefaultRetries 默认单个切片上传失败后的重试次数
+	DefaultRetries = 3
 )
 
+// 切片上传失败后，第 n 次重试前等待 n 倍的该时长
+var retryInterval = time.Second
+
 var client = dohttp.New(false, false)
 
 // GetYikeReq 获取适配的网站的 Req，将用于创建 BDFile
@@ -60,14 +68,23 @@ func GetYikeReq(cookie string, bdstoken string) *Req {
 // 参数 bdstoken Terabox 不需要，传到空""即可
 func GetTeraboxReq(cookie string) *Req {
 	return &Req{
+		RapidURL: "https://www.terabox.com/api/rapidupload?app_id=250528&web=1&channel=dubox&" +
+			"clienttype=0",
 		PrecreateURL: "https://www.terabox.com/api/precreate",
 		SuperfileURL: "https://c-jp.terabox.com/rest/2.0/pcs/superfile2?method=upload&app_id=250528&" +
 			"channel=dubox&clienttype=0&web=1&logid=MTY3ODc5NjA3MDg0MjAuODU3Mjc0MjM3NzAxNTQ2OA==&" +
 			"uploadsign=0&path=%s&uploadid=%s&partseq=%d",
 		CreateURL: "https://www.terabox.com/api/create?isdir=0&rtype=1&app_id=250528&web=1&" +
 			"channel=dubox&clienttype=0",
-		ListURL: "",
-		DelURL:  "",
+		ListURL: "https://www.terabox.com/api/list?app_id=250528&web=1&channel=dubox&clienttype=0&" +
+			"order=name",
+		SearchURL: "https://www.terabox.com/api/search?app_id=250528&web=1&channel=dubox&clienttype=0",
+		MetaURL:   "https://www.terabox.com/api/filemetas?app_id=250528&web=1&channel=dubox&clienttype=0",
+		MkdirURL: "https://www.terabox.com/api/create?a=commit&app_id=250528&web=1&channel=dubox&" +
+			"clienttype=0",
+		ManageURL: "https://www.terabox.com/api/filemanager?app_id=250528&web=1&channel=dubox&" +
+			"clienttype=0&async=0&opera=%s",
+		DelURL: "",
 
 		Headers: map[string]string{
 			"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
@@ -136,6 +153,9 @@ func NewPath(path string, remotePath string, req *Req) (*BDFile, error) {
 
 // UploadFile 上传文件到一刻相册
 //
+// 设置了 StatePath 时，会在每个切片上传完成后保存进度；中断后再次上传同一文件，将沿用之前的 uploadid，
+// 只上传未完成的切片。上传成功后删除状态文件
+//
 // 读取大文件 https://learnku.com/articles/23559/two-schemes-for-reading-golang-super-large-files
 func (f *BDFile) UploadFile() error {
 	// 关闭由 os.Open() 打开的文件
@@ -145,71 +165,204 @@ func (f *BDFile) UploadFile() error {
 		}
 	}()
 
-	// 预创建
-	resp, err := f.precreate()
+	// 计算摘要
+	err := f.digest()
 	if err != nil {
 		return err
 	}
-	// type 为 2或3，都表示云端已有该文件，经过“预创建”，已经“秒传”，直接返回
-	if resp.ReturnType == 2 || resp.ReturnType == 3 {
-		return nil
-	}
-	// 此后，为 1 表示云端没有改文件，需要发送；其它 type 为未知的响应
-	if resp.ReturnType != 1 {
-		return fmt.Errorf("未知的响应 ReturnType：%+v", resp)
+
+	// 读取之前的上传进度
+	state, err := f.loadState()
+	if err != nil {
+		return err
 	}
 
-	// 云端没有该文件，需要发送
-	// 循环发送文件的所有切片
-
-	// 下面会填满数据，不必 make([]byte, 0, splitSize)
-	bs := make([]byte, splitSize)
-	seq := 0
-	for {
-		// 先清空原内容
-		n, err := f.Reader.Read(bs[:])
-		// 读取出错
-		if n < 0 {
+	if state == nil {
+		// 秒传
+		f.Rapid, err = f.rapidupload()
+		if err != nil {
 			return err
 		}
-		// 已读完
-		if n == 0 {
-			break
+		if f.Rapid {
+			return nil
 		}
 
-		// 发送切片
-		// 不能直接写 bs，因为最后一次读取不一定填满 bs
-		err = f.superfile(bs[0:n], seq, resp.Uploadid)
+		// 预创建
+		resp, err := f.precreate()
 		if err != nil {
 			return err
 		}
+		// type 为 2或3，都表示云端已有该文件，经过“预创建”，已经“秒传”，直接返回
+		if resp.ReturnType == 2 || resp.ReturnType == 3 {
+			f.Rapid = true
+			return nil
+		}
+		// 此后，为 1 表示云端没有改文件，需要发送；其它 type 为未知的响应
+		if resp.ReturnType != 1 {
+			return fmt.Errorf("未知的响应 ReturnType：%+v", resp)
+		}
 
-		f.BlockMD5List = append(f.BlockMD5List, fmt.Sprintf("%x", md5.Sum(bs[0:n])))
+		state = &uploadState{
+			RemotePath: f.RemotePath,
+			Size:       f.Size,
+			ContentMD5: f.ContentMD5,
+			Uploadid:   resp.Uploadid,
+			Blocks:     make(map[int]string),
+			Created:    time.Now().Unix(),
+		}
+		err = f.saveState(state)
+		if err != nil {
+			return err
+		}
+	}
 
-		// 继续读取文件
-		seq++
+	// 云端没有该文件，需要发送
+	err = f.uploadBlocks(state)
+	if err != nil {
+		return err
 	}
 
 	// 已发送所有切片，开始创建文件
-	return f.create(resp.Uploadid)
+	err = f.create(state.Uploadid)
+	if err != nil {
+		return err
+	}
+
+	return f.removeState()
 }
 
-// 1. 预处理数据文件
-func (f *BDFile) precreate() (*PreResp, error) {
-	// 设置该文件是单个切片，还是有多个切片
-	if f.Size <= splitSize {
-		f.BlockListMd5 = tagOneSeq
-	} else {
-		f.BlockListMd5 = tagMoreSeq
+// 待上传的切片
+type block struct {
+	seq  int
+	md5  string
+	data []byte
+}
+
+// 读取文件的所有切片，并由多个协程同时上传
+//
+// 切片的 MD5 已在计算摘要时得到；状态中记录已上传、且 MD5 相同的切片将跳过
+func (f *BDFile) uploadBlocks(state *uploadState) error {
+	workers := f.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	// 状态会被上传的协程修改，先复制已完成的切片
+	uploaded := make(map[int]string, len(state.Blocks))
+	for seq, sum := range state.Blocks {
+		uploaded[seq] = sum
+	}
+
+	// 任一切片失败后，停止读取、上传后续的切片
+	var (
+		mu       sync.Mutex
+		firstErr error
+		once     sync.Once
+		failed   = make(chan struct{})
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(failed)
+		})
+	}
+
+	// 无缓冲，同时在内存中的切片不超过 workers+1 个
+	blocks := make(chan *block)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range blocks {
+				// 已有切片失败，不再上传
+				select {
+				case <-failed:
+					continue
+				default:
+				}
+
+				err := f.superfileRetry(b.data, b.seq, state.Uploadid)
+				if err != nil {
+					fail(err)
+					continue
+				}
+
+				mu.Lock()
+				state.Blocks[b.seq] = b.md5
+				err = f.saveState(state)
+				mu.Unlock()
+				if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	var readErr error
+loop:
+	for seq := 0; ; seq++ {
+		// 上传的协程会持有切片，每次都需要新的空间
+		bs := make([]byte, splitSize)
+		n, err := io.ReadFull(f.Reader, bs)
+		// 已读完
+		if err == io.EOF {
+			break
+		}
+		// 读取出错。最后一个切片不足 splitSize 时为 io.ErrUnexpectedEOF
+		if err != nil && err != io.ErrUnexpectedEOF {
+			readErr = fmt.Errorf("读取切片 %d 出错：%w", seq, err)
+			break
+		}
+
+		// 文件比计算摘要时长
+		if seq >= len(f.BlockMD5List) {
+			readErr = fmt.Errorf("切片 %d 超出计算摘要时的切片数，文件内容已改变", seq)
+			break
+		}
+		// 文件在计算摘要后被修改，create 时的 MD5 将与上传的数据不符
+		sum := fmt.Sprintf("%x", md5.Sum(bs[:n]))
+		if sum != f.BlockMD5List[seq] {
+			readErr = fmt.Errorf("切片 %d 的 MD5 与计算摘要时不同，文件内容已改变", seq)
+			break
+		}
+
+		// 之前未上传过，或内容已改变
+		if uploaded[seq] != sum {
+			select {
+			case blocks <- &block{seq: seq, md5: sum, data: bs[:n]}:
+			case <-failed:
+				break loop
+			}
+		}
+
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
 	}
 
+	close(blocks)
+	wg.Wait()
+
+	if readErr != nil {
+		return readErr
+	}
+	return firstErr
+}
+
+// 1. 预处理数据文件
+func (f *BDFile) precreate() (*PreResp, error) {
 	// 创建表单
 	// "rtype"的值需要为"3"（覆盖文件）
 	form := url.Values{}
+	form.Add("isdir", "0")
 	form.Add("autoinit", "1")
 	form.Add("path", url.QueryEscape(f.RemotePath))
 
+	form.Add("size", fmt.Sprintf("%d", f.Size))
 	form.Add("block_list", f.BlockListMd5)
+	form.Add("content-md5", f.ContentMD5)
+	form.Add("slice-md5", f.SliceMD5)
 	form.Add("local_ctime", fmt.Sprintf("%d", f.LocalCtime))
 	// form.Add("local_mtime", fmt.Sprintf("%d", time.Now().Unix()))
 
@@ -228,12 +381,38 @@ func (f *BDFile) precreate() (*PreResp, error) {
 
 	// 响应不符合要求
 	if resp.Errno != 0 {
-		return &resp, fmt.Errorf("预上传切片失败：%s", string(bs))
+		return &resp, &errnoError{errno: resp.Errno, msg: fmt.Sprintf("预上传切片失败：%s", string(bs))}
 	}
 
 	return &resp, nil
 }
 
+// 上传切片，失败时重试
+func (f *BDFile) superfileRetry(bs []byte, seq int, uploadid string) error {
+	retries := f.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+	// 为负数时不重试，但仍需上传一次
+	if retries < 0 {
+		retries = 0
+	}
+
+	var err error
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * retryInterval)
+		}
+
+		err = f.superfile(bs, seq, uploadid)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("切片 %d 上传失败：%w", seq, err)
+}
+
 // 2. 上传切片
 //
 // @see https://stackoverflow.com/questions/52696921/reading-bytes-into-go-buffer-with-a-fixed-stride-size
@@ -243,7 +422,7 @@ func (f *BDFile) superfile(bs []byte, seq int, uploadid string) error {
 	file := map[string]interface{}{"file": bs}
 	bs, err := client.PostFiles(u, file, nil, f.Req.Headers)
 	if err != nil {
-		return fmt.Errorf("上传切片出错：%s", err)
+		return fmt.Errorf("上传切片出错：%w", err)
 	}
 
 	// 解析结果
@@ -291,67 +470,18 @@ func (f *BDFile) create(uploadid string) error {
 	}
 
 	if cResp.Errno != 0 {
-		return fmt.Errorf("创建文件失败：%s", string(bs))
+		return &errnoError{errno: cResp.Errno, msg: fmt.Sprintf("创建文件失败：%s", string(bs))}
 	}
 
 	return nil
 }
 
-// DelAll 删除所有文件
-func DelAll(req *Req) error {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	for {
-		// 列出文件
-		bs, err := client.GetBytes(req.ListURL, req.Headers)
-		if err != nil {
-			return fmt.Errorf("列出文件出错：%w", err)
-		}
-
-		var files FilesResp
-		err = json.Unmarshal(bs, &files)
-		if err != nil {
-			return fmt.Errorf("解析文件列表出错：%w", err)
-		}
-
-		if files.Errno != 0 {
-			return fmt.Errorf("列出文件失败：%s\n", string(bs))
-		}
-
-		// 删除文件
-		fidList := make([]int64, len(files.List))
-		for i, f := range files.List {
-			fidList[i] = f.Fsid
-		}
-		bs, err = json.Marshal(fidList)
-		if err != nil {
-			return fmt.Errorf("序列化文件的 ID 列表时出错：%w", err)
-		}
-
-		u := fmt.Sprintf(req.DelURL, string(bs))
-		bs, err = client.GetBytes(u, req.Headers)
-		if err != nil {
-			return fmt.Errorf("删除文件出错：%w", err)
-		}
-
-		var resp PreResp
-		err = json.Unmarshal(bs, &resp)
-		if err != nil {
-			return fmt.Errorf("解析删除文件的响应时出错：%w", err)
-		}
-
-		if resp.Errno == 2 {
-			break
-		}
-		if resp.Errno != 0 {
-			return fmt.Errorf("删除一刻相册中所有的图片失败：%s", string(bs))
-		}
-
-		fmt.Printf("已删除该页图片，将继续删除下页\n")
-
-		r := rand.Intn(5)
-		time.Sleep(time.Duration(r+1) * time.Second)
-	}
+// 响应中的 errno 不为 0 时的错误。可据此判断是否因 access_token 失效而失败
+type errnoError struct {
+	errno int
+	msg   string
+}
 
-	return nil
+func (e *errnoError) Error() string {
+	return e.msg
 }
@@ -1,7 +1,21 @@
 package dobdpan
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -43,3 +57,249 @@ func TestDelAll(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// 模拟上传接口的服务
+type fakePan struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// 云端已有的文件内容的 MD5，可秒传
+	rapid map[string]bool
+	// precreate 的请求次数、最近一次的表单
+	precreates int
+	preForm    url.Values
+	// 各切片收到的数据、上传次数
+	parts  map[int][]byte
+	counts map[int]int
+	// 各切片还需返回失败的次数
+	fails map[int]int
+	// create 请求中的 block_list
+	blockList []string
+}
+
+func newFakePan(t *testing.T) *fakePan {
+	p := &fakePan{rapid: make(map[string]bool), parts: make(map[int][]byte), counts: make(map[int]int),
+		fails: make(map[int]int)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rapidupload", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.rapid[r.FormValue("content-md5")] {
+			w.Write([]byte(`{"errno":0}`))
+			return
+		}
+		w.Write([]byte(`{"errno":404}`))
+	})
+	mux.HandleFunc("/precreate", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		p.precreates++
+		p.preForm = r.PostForm
+		p.mu.Unlock()
+		w.Write([]byte(`{"errno":0,"return_type":1,"uploadid":"up1"}`))
+	})
+	mux.HandleFunc("/superfile", func(w http.ResponseWriter, r *http.Request) {
+		seq, _ := strconv.Atoi(r.URL.Query().Get("partseq"))
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		bs, _ := io.ReadAll(file)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.counts[seq]++
+		if p.fails[seq] > 0 {
+			p.fails[seq]--
+			w.Write([]byte(`{"error_code":31299,"error_msg":"fail"}`))
+			return
+		}
+		p.parts[seq] = bs
+		fmt.Fprintf(w, `{"md5":"%x","partseq":"%d","uploadid":"%s"}`,
+			md5.Sum(bs), seq, r.URL.Query().Get("uploadid"))
+	})
+	mux.HandleFunc("/create", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		json.Unmarshal([]byte(r.FormValue("block_list")), &p.blockList)
+		w.Write([]byte(`{"errno":0}`))
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *fakePan) req() *Req {
+	return &Req{
+		RapidURL:     p.URL + "/rapidupload",
+		PrecreateURL: p.URL + "/precreate",
+		SuperfileURL: p.URL + "/superfile?path=%s&uploadid=%s&partseq=%d",
+		CreateURL:    p.URL + "/create",
+	}
+}
+
+func TestBDFile_UploadFileResume(t *testing.T) {
+	retryInterval = 0
+	p := newFakePan(t)
+
+	bs := make([]byte, 2*splitSize+100)
+	rand.Read(bs)
+	state := filepath.Join(t.TempDir(), "state.json")
+
+	// 切片 1 始终失败，上传中断
+	p.fails[1] = 100
+	f := NewBytes(bs, "/Test/a.bin", p.req(), 0)
+	f.StatePath, f.Workers, f.Retries = state, 1, -1
+	if err := f.UploadFile(); err == nil {
+		t.Fatal("应上传失败")
+	}
+	saved, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatalf("应保存上传进度：%s", err)
+	}
+	var st uploadState
+	if err = json.Unmarshal(saved, &st); err != nil {
+		t.Fatal(err)
+	}
+	sum0 := fmt.Sprintf("%x", md5.Sum(bs[:splitSize]))
+	if len(st.Blocks) != 1 || st.Blocks[0] != sum0 {
+		t.Errorf("上传进度中应只记录切片 0：%v", st.Blocks)
+	}
+	if p.counts[0] != 1 || p.counts[1] != 1 {
+		t.Errorf("不重试时切片 0、1 应各上传 1 次，实际为 %v", p.counts)
+	}
+
+	// 续传，切片 2 失败一次后重试成功
+	p.fails[1], p.fails[2] = 0, 1
+	f = NewBytes(bs, "/Test/a.bin", p.req(), 0)
+	f.StatePath, f.Workers, f.Retries = state, 3, 1
+	if err := f.UploadFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.precreates != 1 {
+		t.Errorf("续传时不应重新预创建，precreate 请求了 %d 次", p.precreates)
+	}
+	if p.counts[0] != 1 {
+		t.Errorf("已上传的切片 0 不应重传，实际上传了 %d 次", p.counts[0])
+	}
+	if p.counts[2] != 2 {
+		t.Errorf("切片 2 应重试 1 次，实际上传了 %d 次", p.counts[2])
+	}
+
+	// create 中切片的顺序与文件一致
+	want := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		end := (i + 1) * splitSize
+		if end > len(bs) {
+			end = len(bs)
+		}
+		want = append(want, fmt.Sprintf("%x", md5.Sum(bs[i*splitSize:end])))
+		if !bytes.Equal(p.parts[i], bs[i*splitSize:end]) {
+			t.Errorf("切片 %d 的数据不符", i)
+		}
+	}
+	if !reflect.DeepEqual(p.blockList, want) {
+		t.Errorf("block_list 不符：%v，应为 %v", p.blockList, want)
+	}
+
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Errorf("上传完成后应删除状态文件")
+	}
+}
+
+func TestBDFile_Digest(t *testing.T) {
+	bs := make([]byte, splitSize+100)
+	rand.Read(bs)
+
+	f := NewBytes(bs, "/Test/a.bin", nil, 0)
+	if err := f.digest(); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.ContentMD5 != fmt.Sprintf("%x", md5.Sum(bs)) {
+		t.Errorf("全文 MD5 不符：%s", f.ContentMD5)
+	}
+	if f.SliceMD5 != fmt.Sprintf("%x", md5.Sum(bs[:md5Size])) {
+		t.Errorf("前 256KB 的 MD5 不符：%s", f.SliceMD5)
+	}
+	if f.ContentCRC32 != crc32.ChecksumIEEE(bs) {
+		t.Errorf("CRC32 不符：%d", f.ContentCRC32)
+	}
+	want := []string{fmt.Sprintf("%x", md5.Sum(bs[:splitSize])), fmt.Sprintf("%x", md5.Sum(bs[splitSize:]))}
+	if !reflect.DeepEqual(f.BlockMD5List, want) {
+		t.Errorf("切片的 MD5 不符：%v", f.BlockMD5List)
+	}
+
+	// 计算后回到开头，上传时能读取完整的内容
+	rest, _ := io.ReadAll(f.Reader)
+	if !bytes.Equal(rest, bs) {
+		t.Errorf("计算摘要后未回到读取前的位置")
+	}
+
+	// 不足 256KB 时，前 256KB 的 MD5 即全文的 MD5
+	f = NewBytes([]byte("abc"), "/Test/b.txt", nil, 0)
+	if err := f.digest(); err != nil {
+		t.Fatal(err)
+	}
+	if f.SliceMD5 != f.ContentMD5 {
+		t.Errorf("前 256KB 的 MD5 应与全文相同：%s", f.SliceMD5)
+	}
+}
+
+func TestBDFile_UploadFileRapid(t *testing.T) {
+	p := newFakePan(t)
+
+	bs := make([]byte, splitSize+100)
+	rand.Read(bs)
+	sum := fmt.Sprintf("%x", md5.Sum(bs))
+
+	// 云端没有时，正常上传，并在 precreate 中发送真实的摘要
+	f := NewBytes(bs, "/Test/a.bin", p.req(), 0)
+	if err := f.UploadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Rapid {
+		t.Errorf("云端没有该文件，不应为秒传")
+	}
+	if p.preForm.Get("content-md5") != sum || p.preForm.Get("block_list") != f.BlockListMd5 {
+		t.Errorf("precreate 的摘要不符：%v", p.preForm)
+	}
+
+	// 云端已有时，秒传，不再上传
+	p.rapid[sum] = true
+	f = NewBytes(bs, "/Test/b.bin", p.req(), 0)
+	if err := f.UploadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Rapid {
+		t.Errorf("云端已有该文件，应为秒传")
+	}
+	if p.precreates != 1 || p.counts[0] != 1 {
+		t.Errorf("秒传时不应预创建、上传切片")
+	}
+}
+
+func TestBDFile_UploadBlocksChanged(t *testing.T) {
+	p := newFakePan(t)
+
+	bs := make([]byte, splitSize+100)
+	rand.Read(bs)
+	f := NewBytes(bs, "/Test/a.bin", p.req(), 0)
+	if err := f.digest(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 计算摘要后，文件被修改
+	bs[splitSize] ^= 0xff
+	err := f.uploadBlocks(&uploadState{Uploadid: "up1", Blocks: make(map[int]string)})
+	if err == nil {
+		t.Fatal("文件内容改变时应返回错误")
+	}
+	if _, ok := p.parts[1]; ok {
+		t.Errorf("内容已改变的切片不应上传")
+	}
+}
@@ -0,0 +1,274 @@
+package dobdpan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// OAuthAddr 开放平台授权的地址
+	OAuthAddr = "https://openapi.baidu.com"
+	// PanAddr 网盘 API 的地址
+	PanAddr = "https://pan.baidu.com"
+	// PCSAddr 上传切片的地址
+	PCSAddr = "https://d.pcs.baidu.com"
+
+	urlNas       = "/rest/2.0/xpan/nas"
+	urlFile      = "/rest/2.0/xpan/file"
+	urlQuota     = "/api/quota"
+	urlSuperfile = "/rest/2.0/pcs/superfile2"
+
+	// ListLimit 列出文件时，每页的最大数量
+	ListLimit = 1000
+)
+
+// 官方要求网盘 API 的 User-Agent 为"pan.baidu.com"
+var xpanHeaders = map[string]string{"User-Agent": "pan.baidu.com"}
+
+// Client 百度网盘开放平台的客户端，使用 access_token 调用官方 API，不依赖浏览器的 Cookie
+//
+// 需先在开放平台创建应用，得到 AppKey、SecretKey。首次使用时调用 Authorize 授权，之后会自动刷新、保存 token
+//
+// 上传的文件只能保存在"/apps/应用名/"下
+//
+// @see https://pan.baidu.com/union/doc/
+type Client struct {
+	appKey    string
+	secretKey string
+
+	// 授权、网盘 API、上传切片的地址
+	oauthAddr string
+	panAddr   string
+	pcsAddr   string
+
+	store TokenStore
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewClient 创建开放平台的客户端
+//
+// store 保存授权，为 nil 时只保存在内存中，程序重启后需重新授权
+func NewClient(appKey string, secretKey string, store TokenStore) *Client {
+	return &Client{
+		appKey:    appKey,
+		secretKey: secretKey,
+		oauthAddr: OAuthAddr,
+		panAddr:   PanAddr,
+		pcsAddr:   PCSAddr,
+		store:     store,
+	}
+}
+
+// SetAddr 设置授权、网盘 API、上传切片的地址，可用于测试
+func (c *Client) SetAddr(oauthAddr string, panAddr string, pcsAddr string) {
+	c.oauthAddr = oauthAddr
+	c.panAddr = panAddr
+	c.pcsAddr = pcsAddr
+}
+
+// 网盘 API 响应中的错误信息
+type xpanErr struct {
+	// 不为 0 即表示有错
+	Errno  int    `json:"errno"`
+	Errmsg string `json:"errmsg"`
+}
+
+// 是否为 access_token 失效的错误码
+func isTokenErrno(errno int) bool {
+	// -6：身份验证失败；111：access_token 失效
+	return errno == -6 || errno == 111
+}
+
+// 调用网盘 API，并将响应解析到 result
+//
+// form 为 nil 时发送 GET 请求，否则 POST 表单。access_token 失效时将刷新后重试一次
+func (c *Client) call(apiURL string, query url.Values, form url.Values, result interface{}) error {
+	stale := ""
+	for i := 0; ; i++ {
+		token, err := c.accessToken(stale)
+		if err != nil {
+			return err
+		}
+
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("access_token", token)
+		u := c.panAddr + apiURL + "?" + q.Encode()
+
+		var bs []byte
+		if form == nil {
+			bs, err = client.GetBytes(u, xpanHeaders)
+		} else {
+			bs, err = client.PostForm(u, form.Encode(), xpanHeaders)
+		}
+		if err != nil {
+			return fmt.Errorf("请求网盘 API 出错：%w", err)
+		}
+
+		var e xpanErr
+		err = json.Unmarshal(bs, &e)
+		if err != nil {
+			return fmt.Errorf("解析网盘 API 的响应出错：%w ==> %s", err, string(bs))
+		}
+
+		if isTokenErrno(e.Errno) && i == 0 {
+			stale = token
+			continue
+		}
+		if e.Errno != 0 {
+			return fmt.Errorf("网盘 API 返回错误：%s", string(bs))
+		}
+
+		if result == nil {
+			return nil
+		}
+		err = json.Unmarshal(bs, result)
+		if err != nil {
+			return fmt.Errorf("解析网盘 API 的响应出错：%w ==> %s", err, string(bs))
+		}
+
+		return nil
+	}
+}
+
+// UserInfo 网盘的用户信息
+type UserInfo struct {
+	BaiduName   string `json:"baidu_name"`
+	NetdiskName string `json:"netdisk_name"`
+	AvatarURL   string `json:"avatar_url"`
+	// 0：普通用户；1：普通会员；2：超级会员
+	VipType int   `json:"vip_type"`
+	UK      int64 `json:"uk"`
+}
+
+// Quota 网盘的容量（字节）
+type Quota struct {
+	Total int64 `json:"total"`
+	Used  int64 `json:"used"`
+	Free  int64 `json:"free"`
+	// 7 天内是否有容量到期
+	Expire bool `json:"expire"`
+}
+
+// UserInfo 获取用户信息
+func (c *Client) UserInfo() (*UserInfo, error) {
+	var info UserInfo
+	err := c.call(urlNas, url.Values{"method": []string{"uinfo"}}, nil, &info)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息出错：%w", err)
+	}
+
+	return &info, nil
+}
+
+// Quota 获取网盘的容量
+func (c *Client) Quota() (*Quota, error) {
+	query := url.Values{"checkfree": []string{"1"}, "checkexpire": []string{"1"}}
+	var quota Quota
+	err := c.call(urlQuota, query, nil, &quota)
+	if err != nil {
+		return nil, fmt.Errorf("获取网盘容量出错：%w", err)
+	}
+
+	return &quota, nil
+}
+
+// List 列出文件夹下的文件
+//
+// start 从第几个开始，limit 最多返回的数量，不超过 ListLimit。返回的数量小于 limit 时表示已列完
+func (c *Client) List(dir string, start int, limit int) ([]*FileInfo, error) {
+	query := url.Values{
+		"method": []string{"list"},
+		"dir":    []string{dir},
+		"order":  []string{"name"},
+		"start":  []string{strconv.Itoa(start)},
+		"limit":  []string{strconv.Itoa(limit)},
+		"web":    []string{"web"},
+	}
+	var resp struct {
+		List []*FileInfo `json:"list"`
+	}
+	err := c.call(urlFile, query, nil, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("列出文件出错：%w", err)
+	}
+
+	return resp.List, nil
+}
+
+// Req 获取使用开放平台 API 的 Req，可用于创建 BDFile 上传文件，或列出、搜索、创建文件夹、复制、移动、重命名、
+// 按路径删除文件
+//
+// 开放平台的 filemetas 只能按 fsid 查询，删除只能按路径，与 Req.Meta、Req.DeleteByID 的参数不符，所以未设置
+// MetaURL、DelURL，这两个操作将返回 ErrUnsupported
+//
+// access_token 包含在 URL 中，应在使用前获取；token 刷新后，旧的 Req 将失效，需重新获取
+func (c *Client) Req() (*Req, error) {
+	req, _, err := c.req("")
+	return req, err
+}
+
+// 获取 Req，及其使用的 access_token。stale 为已失效的 access_token，与当前的相同时将先刷新
+func (c *Client) req(stale string) (*Req, string, error) {
+	token, err := c.accessToken(stale)
+	if err != nil {
+		return nil, "", err
+	}
+	t := url.QueryEscape(token)
+	// 作为格式字符串的 URL 需转义"%"
+	tf := strings.ReplaceAll(t, "%", "%%")
+
+	// 复制请求头，以免调用方修改后影响其它 Req
+	headers := make(map[string]string, len(xpanHeaders))
+	for k, v := range xpanHeaders {
+		headers[k] = v
+	}
+
+	return &Req{
+		PrecreateURL: c.panAddr + urlFile + "?method=precreate&access_token=" + t,
+		SuperfileURL: c.pcsAddr + urlSuperfile + "?method=upload&type=tmpfile&access_token=" + tf +
+			"&path=%s&uploadid=%s&partseq=%d",
+		CreateURL: c.panAddr + urlFile + "?method=create&access_token=" + t,
+
+		ListURL:   c.panAddr + urlFile + "?method=list&order=name&web=web&access_token=" + t,
+		SearchURL: c.panAddr + urlFile + "?method=search&access_token=" + t,
+		MkdirURL:  c.panAddr + urlFile + "?method=create&access_token=" + t,
+		ManageURL: c.panAddr + urlFile + "?method=filemanager&async=0&access_token=" + tf + "&opera=%s",
+
+		Headers: headers,
+	}, token, nil
+}
+
+// UploadFile 上传本地文件到网盘
+//
+// remotePath 需在"/apps/应用名/"下。access_token 失效时将刷新后重新上传一次
+func (c *Client) UploadFile(path string, remotePath string) error {
+	stale := ""
+	for i := 0; ; i++ {
+		req, token, err := c.req(stale)
+		if err != nil {
+			return err
+		}
+
+		f, err := NewPath(path, remotePath, req)
+		if err != nil {
+			return err
+		}
+
+		err = f.UploadFile()
+		var e *errnoError
+		if errors.As(err, &e) && isTokenErrno(e.errno) && i == 0 {
+			stale = token
+			continue
+		}
+		return err
+	}
+}
@@ -0,0 +1,316 @@
+package dobdpan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 模拟开放平台的服务
+type fakeXPan struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// 设备码还需返回未授权的次数
+	pending int
+	// 当前有效的 token，每次刷新后加 1
+	n int
+	// 已上传的文件
+	files []*FileInfo
+	parts map[string][]byte
+}
+
+func newFakeXPan(t *testing.T) *fakeXPan {
+	x := &fakeXPan{parts: make(map[string][]byte)}
+
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		bs, _ := json.Marshal(v)
+		w.Write(bs)
+	}
+	tokenOf := func(n int) map[string]interface{} {
+		return map[string]interface{}{
+			"access_token":  fmt.Sprintf("at%d", n),
+			"refresh_token": fmt.Sprintf("rt%d", n),
+			"expires_in":    2592000,
+			"scope":         "basic netdisk",
+		}
+	}
+	// 校验 access_token，失效时返回 false
+	auth := func(w http.ResponseWriter, r *http.Request) bool {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		if r.URL.Query().Get("access_token") != fmt.Sprintf("at%d", x.n) {
+			w.Write([]byte(`{"errno":-6,"errmsg":"invalid token"}`))
+			return false
+		}
+		return true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(urlDeviceCode, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"device_code": "dc", "user_code": "uc",
+			"verification_url": "https://openapi.baidu.com/device", "expires_in": 300, "interval": 1})
+	})
+	mux.HandleFunc(urlToken, func(w http.ResponseWriter, r *http.Request) {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+
+		q := r.URL.Query()
+		switch q.Get("grant_type") {
+		case "device_token":
+			if x.pending > 0 {
+				x.pending--
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"authorization_pending","error_description":"User has not yet authorized"}`))
+				return
+			}
+		case "refresh_token":
+			if q.Get("refresh_token") != fmt.Sprintf("rt%d", x.n) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"expired_token","error_description":"refresh token has been used"}`))
+				return
+			}
+		}
+		x.n++
+		writeJSON(w, tokenOf(x.n))
+	})
+	mux.HandleFunc(urlNas, func(w http.ResponseWriter, r *http.Request) {
+		if auth(w, r) {
+			w.Write([]byte(`{"errno":0,"baidu_name":"test","netdisk_name":"test","vip_type":2,"uk":123}`))
+		}
+	})
+	mux.HandleFunc(urlQuota, func(w http.ResponseWriter, r *http.Request) {
+		if auth(w, r) {
+			w.Write([]byte(`{"errno":0,"total":100,"used":40,"free":60,"expire":false}`))
+		}
+	})
+	mux.HandleFunc(urlFile, func(w http.ResponseWriter, r *http.Request) {
+		if !auth(w, r) {
+			return
+		}
+
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		switch r.URL.Query().Get("method") {
+		case "precreate":
+			w.Write([]byte(`{"errno":0,"return_type":1,"uploadid":"up1"}`))
+		case "create":
+			var size int64
+			fmt.Sscan(r.FormValue("size"), &size)
+			f := &FileInfo{FSID: int64(len(x.files) + 1), Path: r.FormValue("path"),
+				ServerFilename: filepath.Base(r.FormValue("path")), Size: size}
+			x.files = append(x.files, f)
+			writeJSON(w, map[string]interface{}{"errno": 0, "fs_id": f.FSID, "path": f.Path})
+		case "list":
+			var start, limit int
+			fmt.Sscan(r.URL.Query().Get("start"), &start)
+			fmt.Sscan(r.URL.Query().Get("limit"), &limit)
+			list := make([]*FileInfo, 0)
+			for i := start; i < start+limit && i < len(x.files); i++ {
+				list = append(list, x.files[i])
+			}
+			writeJSON(w, map[string]interface{}{"errno": 0, "list": list})
+		}
+	})
+	mux.HandleFunc(urlSuperfile, func(w http.ResponseWriter, r *http.Request) {
+		if !auth(w, r) {
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		bs, _ := io.ReadAll(file)
+
+		x.mu.Lock()
+		x.parts[r.URL.Query().Get("partseq")] = bs
+		x.mu.Unlock()
+		w.Write([]byte(`{"md5":"x","request_id":1}`))
+	})
+
+	x.Server = httptest.NewServer(mux)
+	t.Cleanup(x.Close)
+	return x
+}
+
+func newFakeClient(x *fakeXPan, store TokenStore) *Client {
+	c := NewClient("key", "secret", store)
+	c.SetAddr(x.URL, x.URL, x.URL)
+	return c
+}
+
+func TestClient_Authorize(t *testing.T) {
+	pollUnit = time.Millisecond
+	x := newFakeXPan(t)
+	x.pending = 2
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	c := newFakeClient(x, NewFileTokenStore(path))
+	if _, err := c.UserInfo(); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("未授权时应返回 ErrNotAuthorized，实际为 %v", err)
+	}
+
+	var shown *DeviceCode
+	err := c.Authorize(func(code *DeviceCode) { shown = code })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shown == nil || shown.UserCode != "uc" {
+		t.Errorf("应展示授权信息：%+v", shown)
+	}
+
+	// 重新创建的客户端使用保存的 token
+	c = newFakeClient(x, NewFileTokenStore(path))
+	info, err := c.UserInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.UK != 123 {
+		t.Errorf("用户信息不符：%+v", info)
+	}
+}
+
+func TestClient_Refresh(t *testing.T) {
+	x := newFakeXPan(t)
+	x.n = 1
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStore(path)
+
+	// access_token 已过期，调用前刷新
+	store.Save(&Token{AccessToken: "at1", RefreshToken: "rt1", Expires: time.Now().Unix() - 1})
+	c := newFakeClient(x, store)
+	quota, err := c.Quota()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.Free != 60 {
+		t.Errorf("容量不符：%+v", quota)
+	}
+
+	// 刷新后的 refresh_token 已保存
+	token, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.RefreshToken != "rt2" {
+		t.Errorf("应保存新的 refresh_token，实际为 %s", token.RefreshToken)
+	}
+
+	// 服务端提示 access_token 失效时，刷新后重试
+	x.mu.Lock()
+	x.n++
+	x.mu.Unlock()
+	c = newFakeClient(x, &memStore{token: &Token{AccessToken: "at2", RefreshToken: "rt3",
+		Expires: time.Now().Add(time.Hour).Unix()}})
+	if _, err = c.UserInfo(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_UploadFile(t *testing.T) {
+	x := newFakeXPan(t)
+	x.n = 1
+	c := newFakeClient(x, &memStore{token: &Token{AccessToken: "at1", RefreshToken: "rt1",
+		Expires: time.Now().Add(time.Hour).Unix()}})
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UploadFile(path, "/apps/test/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if string(x.parts["0"]) != "hello" {
+		t.Errorf("上传的数据不符：%s", x.parts["0"])
+	}
+
+	files, err := c.List("/apps/test", 0, ListLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "/apps/test/a.txt" || files[0].Size != 5 {
+		t.Errorf("文件列表不符：%+v", files)
+	}
+
+	// 通过 Req 遍历
+	req, err := c.Req()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	err = req.Walk("/apps/test", func(f *FileInfo) error {
+		paths = append(paths, f.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != "/apps/test/a.txt" {
+		t.Errorf("遍历结果不符：%v", paths)
+	}
+}
+
+func TestClient_UploadFileRefresh(t *testing.T) {
+	x := newFakeXPan(t)
+	// 服务端已使 at1 失效，rt2 仍可用于刷新
+	x.n = 2
+	c := newFakeClient(x, &memStore{token: &Token{AccessToken: "at1", RefreshToken: "rt2",
+		Expires: time.Now().Add(time.Hour).Unix()}})
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// access_token 失效时，刷新后重新上传
+	if err := c.UploadFile(path, "/apps/test/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(x.files) != 1 || string(x.parts["0"]) != "hello" {
+		t.Errorf("上传结果不符：%+v", x.files)
+	}
+}
+
+func TestClient_ReqHeaders(t *testing.T) {
+	x := newFakeXPan(t)
+	x.n = 1
+	c := newFakeClient(x, &memStore{token: &Token{AccessToken: "at1", RefreshToken: "rt1",
+		Expires: time.Now().Add(time.Hour).Unix()}})
+
+	a, err := c.Req()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Headers["Cookie"] = "a=1"
+
+	// 修改一个 Req 的请求头，不应影响其它 Req
+	b, err := c.Req()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Headers["Cookie"]; ok || b.Headers["User-Agent"] != "pan.baidu.com" {
+		t.Errorf("请求头不符：%v", b.Headers)
+	}
+}
+
+// 保存在内存中的授权
+type memStore struct {
+	token *Token
+}
+
+func (s *memStore) Load() (*Token, error) {
+	return s.token, nil
+}
+
+func (s *memStore) Save(token *Token) error {
+	s.token = token
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package doserverchan 通过 Server酱 推送消息到微信等渠道
+//
+// @see https://sct.ftqq.com/
+package doserverchan
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/donething/utils-go/dohttp"
+	"net/url"
+	"regexp"
+)
+
+// Addr Server酱 Turbo 版的地址
+const Addr = "https://sctapi.ftqq.com"
+
+// Server酱³ 的 SendKey，如"sctp123tabc"中的"123"为 uid
+var reKey3 = regexp.MustCompile(`^sctp(\d+)t`)
+
+// Msg 推送的消息
+type Msg struct {
+	// 标题，最长 32 个字符
+	Title string
+	// 正文，支持 Markdown，最长 32KB
+	Desp string
+	// 消息卡片的内容，可空
+	Short string
+	// 是否隐藏调用 IP
+	NoIP bool
+	// 推送的渠道，多个以"|"分隔，如"9|66"。为空时使用网页上设置的渠道
+	Channel string
+}
+
+// ServerChan 推送对象
+type ServerChan struct {
+	addr   string
+	key    string
+	client dohttp.DoClient
+}
+
+// PushResult 推送的响应
+type PushResult struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Pushid  string `json:"pushid"`
+		Readkey string `json:"readkey"`
+	} `json:"data"`
+}
+
+// NewServerChan 创建推送对象
+//
+// key SendKey。Server酱³ 的 SendKey（以"sctp"开头）将自动使用其地址
+func NewServerChan(key string) *ServerChan {
+	addr := Addr
+	if m := reKey3.FindStringSubmatch(key); m != nil {
+		addr = fmt.Sprintf("https://%s.push.ft07.com", m[1])
+	}
+
+	return &ServerChan{addr: addr, key: key, client: dohttp.New(false, false)}
+}
+
+// SetAddr 设置服务的地址，如测试用的模拟服务
+//
+// addr 如 "http://127.0.0.1:8080"，末尾不含"/"
+func (s *ServerChan) SetAddr(addr string) {
+	s.addr = addr
+}
+
+// Push 推送消息
+func (s *ServerChan) Push(msg *Msg) (*PushResult, error) {
+	form := url.Values{"title": {msg.Title}, "desp": {msg.Desp}}
+	if msg.Short != "" {
+		form.Set("short", msg.Short)
+	}
+	if msg.NoIP {
+		form.Set("noip", "1")
+	}
+	if msg.Channel != "" {
+		form.Set("channel", msg.Channel)
+	}
+
+	// Server酱³ 的路径为 /send/<key>.send
+	u := fmt.Sprintf("%s/%s.send", s.addr, s.key)
+	if reKey3.MatchString(s.key) {
+		u = fmt.Sprintf("%s/send/%s.send", s.addr, s.key)
+	}
+
+	bs, err := s.client.PostForm(u, form.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("推送时网络出错：%w", err)
+	}
+
+	var result PushResult
+	err = json.Unmarshal(bs, &result)
+	if err != nil {
+		return nil, fmt.Errorf("解析推送响应 JSON 文本时出错：%w：%s", err, string(bs))
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("推送时出错：%s", string(bs))
+	}
+
+	return &result, nil
+}
+
+// PushText 推送消息
+//
+// desp 正文，支持 Markdown
+func (s *ServerChan) PushText(title string, desp string) error {
+	_, err := s.Push(&Msg{Title: title, Desp: desp})
+	return err
+}
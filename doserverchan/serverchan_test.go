@@ -0,0 +1,40 @@
+package doserverchan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerChan_Push(t *testing.T) {
+	var path, title, channel string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		path, title, channel = r.URL.Path, r.PostForm.Get("title"), r.PostForm.Get("channel")
+		w.Write([]byte(`{"code":0,"message":"","data":{"pushid":"1","readkey":"r"}}`))
+	}))
+	defer srv.Close()
+
+	sc := NewServerChan("SCT123")
+	sc.SetAddr(srv.URL)
+	result, err := sc.Push(&Msg{Title: "标题", Desp: "**正文**", Channel: "9|66"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/SCT123.send" || title != "标题" || channel != "9|66" || result.Data.Pushid != "1" {
+		t.Errorf("推送的数据不符：%s %s %s %+v", path, title, channel, result)
+	}
+
+	// Server酱³
+	sc = NewServerChan("sctp42tabc")
+	if sc.addr != "https://42.push.ft07.com" {
+		t.Errorf("地址不符：%s", sc.addr)
+	}
+	sc.SetAddr(srv.URL)
+	if err = sc.PushText("标题", "正文"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/send/sctp42tabc.send" {
+		t.Errorf("路径不符：%s", path)
+	}
+}
@@ -0,0 +1,187 @@
+package dotg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// BodyFunc 生成请求体及其 Content-Type
+//
+// 每次（重）发送前都会调用，以获取尚未被读取过的数据
+//
+// body 实现了 io.Closer 时，每次发送结束后（无论成功、出错）都会被关闭，可在 Close 中释放资源
+type BodyFunc func() (body io.Reader, contentType string, err error)
+
+// Opener 打开待上传的数据。重发时会再次调用，以重新读取数据
+type Opener func() (io.ReadCloser, error)
+
+// OpenPath 以本地文件路径创建 Opener。上传时才打开文件，上传完毕即关闭
+func OpenPath(path string) Opener {
+	return func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+}
+
+var (
+	// ErrNotRewindable 数据已被读取且无法回到开头，不能重发
+	ErrNotRewindable = errors.New("数据源已被读取且不可回退，无法重发")
+)
+
+// 表单中的文件
+type formFile struct {
+	// 表单字段名
+	field string
+	// 文件名
+	name string
+	// 数据源。可为 Opener、io.Reader
+	src interface{}
+}
+
+// 生成 application/x-www-form-urlencoded 的请求体
+func formBody(params url.Values) BodyFunc {
+	data := params.Encode()
+	return func() (io.Reader, string, error) {
+		return strings.NewReader(data), "application/x-www-form-urlencoded", nil
+	}
+}
+
+// 生成 multipart/form-data 的请求体
+//
+// 使用 bytes.Buffer{} 还是会将数据全部写入内存，所以使用 pipe 替代
+//
+// 重发时，Opener 将被重新打开，io.Seeker 将回到开头，其它已被读取过的 io.Reader 返回 ErrNotRewindable
+func multipartBody(params url.Values, files []formFile) BodyFunc {
+	var (
+		used bool
+		// 上次发送时的请求体，重发前需确保其写入协程已结束，以免与回退数据源冲突
+		prev *pipeBody
+	)
+	return func() (io.Reader, string, error) {
+		// 重发前，先确认所有数据源都可重新读取
+		if used {
+			prev.Close()
+
+			for _, f := range files {
+				if err := rewind(f.src); err != nil {
+					return nil, "", fmt.Errorf("表单字段'%s'：%w", f.field, err)
+				}
+			}
+		}
+		used = true
+
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+
+		prev = &pipeBody{PipeReader: pr, done: make(chan struct{})}
+		go func(done chan struct{}) {
+			defer close(done)
+			// 注意要关闭 pipe writer 否则会卡主
+			pw.CloseWithError(writeMultipart(writer, params, files))
+		}(prev.done)
+
+		return prev, writer.FormDataContentType(), nil
+	}
+}
+
+// multipart 的请求体
+//
+// 发送出错时，服务端可能不再读取数据，写入协程将阻塞在管道上，需关闭后才能结束
+type pipeBody struct {
+	*io.PipeReader
+	// 写入协程结束时关闭
+	done chan struct{}
+}
+
+// Close 关闭管道，并等待写入协程结束。之后才能安全地回退、关闭数据源
+//
+// 可多次调用
+func (b *pipeBody) Close() error {
+	err := b.PipeReader.Close()
+	<-b.done
+	return err
+}
+
+// 写入 multipart 表单
+func writeMultipart(writer *multipart.Writer, params url.Values, files []formFile) error {
+	// 按键名排序，使请求内容固定
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range params[k] {
+			if err := writer.WriteField(k, v); err != nil {
+				return fmt.Errorf("写入表单字段'%s'出错：%w", k, err)
+			}
+		}
+	}
+
+	for _, f := range files {
+		part, err := writer.CreateFormFile(f.field, f.name)
+		if err != nil {
+			return fmt.Errorf("创建文件表单'%s'出错：%w", f.field, err)
+		}
+
+		err = copySrc(part, f.src)
+		if err != nil {
+			return fmt.Errorf("复制文件流'%s'出错：%w", f.field, err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return fmt.Errorf("关闭 multipart writer 出错：%w", err)
+	}
+
+	return nil
+}
+
+// 复制数据源的内容
+func copySrc(dst io.Writer, src interface{}) error {
+	switch s := src.(type) {
+	case Opener:
+		rc, err := s()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		_, err = io.Copy(dst, rc)
+		return err
+	case io.Reader:
+		_, err := io.Copy(dst, s)
+		return err
+	default:
+		return fmt.Errorf("未知的数据源类型：%T", src)
+	}
+}
+
+// 将数据源回退到开头，以便重发
+func rewind(src interface{}) error {
+	switch s := src.(type) {
+	case Opener:
+		return nil
+	case io.Seeker:
+		_, err := s.Seek(0, io.SeekStart)
+		return err
+	default:
+		return ErrNotRewindable
+	}
+}
+
+// 是否为需要作为文件上传的数据源
+func isUpload(src interface{}) bool {
+	switch src.(type) {
+	case Opener, io.Reader:
+		return true
+	default:
+		return false
+	}
+}
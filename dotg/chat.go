@@ -0,0 +1,235 @@
+package dotg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// 群组管理
+//
+// 机器人需为群组的管理员，且拥有相应权限。权限不足时，可用 IsPermissionError() 判断；网络出错时，可用 IsNetworkError() 判断
+
+const (
+	urlGetChat               = "%s/%s/getChat"
+	urlGetChatMember         = "%s/%s/getChatMember"
+	urlGetChatAdministrators = "%s/%s/getChatAdministrators"
+	urlBanChatMember         = "%s/%s/banChatMember"
+	urlRestrictChatMember    = "%s/%s/restrictChatMember"
+	urlSetChatTitle          = "%s/%s/setChatTitle"
+	urlSetChatDescription    = "%s/%s/setChatDescription"
+	urlSetChatPhoto          = "%s/%s/setChatPhoto"
+	urlCreateChatInviteLink  = "%s/%s/createChatInviteLink"
+)
+
+// BanChatMemberParams 封禁群组成员的参数
+type BanChatMemberParams struct {
+	ChatID string
+	UserID int64
+	// 解除封禁的时刻（Unix 时间戳，秒）。为 0 或者少于 30 秒、多于 366 天时，表示永久封禁
+	UntilDate int64
+	// 是否删除该成员在群组中的所有消息
+	RevokeMessages bool
+}
+
+// RestrictChatMemberParams 限制群组成员权限的参数
+type RestrictChatMemberParams struct {
+	ChatID string
+	UserID int64
+	// 限制后的权限
+	Permissions ChatPermissions
+	// 是否按各项权限独立设置。为 false 时，TG 会根据部分权限推导其它权限
+	UseIndependentChatPermissions bool
+	// 解除限制的时刻（Unix 时间戳，秒）。为 0 表示永久
+	UntilDate int64
+}
+
+// CreateChatInviteLinkParams 创建邀请链接的参数
+type CreateChatInviteLinkParams struct {
+	ChatID string
+	// 链接的名称，仅管理员可见
+	Name string
+	// 过期时刻（Unix 时间戳，秒）。为 0 表示不过期
+	ExpireDate int64
+	// 最多可加入的人数（1-99999）。为 0 表示不限制
+	MemberLimit int
+	// 通过链接加入时，是否需管理员批准。为 true 时不能设置 MemberLimit
+	CreatesJoinRequest bool
+}
+
+// GetChat 获取会话的信息
+func (bot *TGBot) GetChat(chatID string) (*Chat, error) {
+	tag := "GetChat"
+	form := url.Values{
+		"chat_id": []string{chatID},
+	}
+
+	var chat Chat
+	err := bot.call(urlGetChat, "", formBody(form), &chat)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return &chat, nil
+}
+
+// GetChatMember 获取群组成员的信息
+func (bot *TGBot) GetChatMember(chatID string, userID int64) (*ChatMember, error) {
+	tag := "GetChatMember"
+	form := url.Values{
+		"chat_id": []string{chatID},
+		"user_id": []string{strconv.FormatInt(userID, 10)},
+	}
+
+	var member ChatMember
+	err := bot.call(urlGetChatMember, "", formBody(form), &member)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return &member, nil
+}
+
+// GetChatAdministrators 获取群组的管理员（不含其它机器人）
+func (bot *TGBot) GetChatAdministrators(chatID string) ([]*ChatMember, error) {
+	tag := "GetChatAdministrators"
+	form := url.Values{
+		"chat_id": []string{chatID},
+	}
+
+	var members []*ChatMember
+	err := bot.call(urlGetChatAdministrators, "", formBody(form), &members)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return members, nil
+}
+
+// BanChatMember 封禁群组成员
+func (bot *TGBot) BanChatMember(params *BanChatMemberParams) error {
+	tag := "BanChatMember"
+	form := url.Values{
+		"chat_id":         []string{params.ChatID},
+		"user_id":         []string{strconv.FormatInt(params.UserID, 10)},
+		"revoke_messages": []string{strconv.FormatBool(params.RevokeMessages)},
+	}
+	if params.UntilDate != 0 {
+		form.Set("until_date", strconv.FormatInt(params.UntilDate, 10))
+	}
+
+	err := bot.call(urlBanChatMember, params.ChatID, formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// RestrictChatMember 限制群组成员的权限。仅适用于超级群组
+func (bot *TGBot) RestrictChatMember(params *RestrictChatMemberParams) error {
+	tag := "RestrictChatMember"
+	bs, err := json.Marshal(params.Permissions)
+	if err != nil {
+		return fmt.Errorf("[%s]序列化权限出错：%w", tag, err)
+	}
+
+	form := url.Values{
+		"chat_id":     []string{params.ChatID},
+		"user_id":     []string{strconv.FormatInt(params.UserID, 10)},
+		"permissions": []string{string(bs)},
+		"use_independent_chat_permissions": []string{
+			strconv.FormatBool(params.UseIndependentChatPermissions)},
+	}
+	if params.UntilDate != 0 {
+		form.Set("until_date", strconv.FormatInt(params.UntilDate, 10))
+	}
+
+	err = bot.call(urlRestrictChatMember, params.ChatID, formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// SetChatTitle 设置群组的名称（1-128 个字符）
+func (bot *TGBot) SetChatTitle(chatID string, title string) error {
+	tag := "SetChatTitle"
+	form := url.Values{
+		"chat_id": []string{chatID},
+		"title":   []string{title},
+	}
+
+	err := bot.call(urlSetChatTitle, chatID, formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// SetChatDescription 设置群组的简介（0-255 个字符）
+func (bot *TGBot) SetChatDescription(chatID string, description string) error {
+	tag := "SetChatDescription"
+	form := url.Values{
+		"chat_id":     []string{chatID},
+		"description": []string{description},
+	}
+
+	err := bot.call(urlSetChatDescription, chatID, formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// SetChatPhoto 设置群组的头像
+//
+// photo 图片数据，可为 Opener、io.Reader。为 io.Reader 时，发送后不会关闭
+func (bot *TGBot) SetChatPhoto(chatID string, photo interface{}) error {
+	tag := "SetChatPhoto"
+	if !isUpload(photo) {
+		return fmt.Errorf("[%s]不支持的图片数据类型：%T", tag, photo)
+	}
+
+	form := url.Values{
+		"chat_id": []string{chatID},
+	}
+	files := []formFile{{field: "photo", name: "photo.jpg", src: photo}}
+
+	err := bot.call(urlSetChatPhoto, chatID, multipartBody(form, files), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// CreateChatInviteLink 创建群组的邀请链接
+func (bot *TGBot) CreateChatInviteLink(params *CreateChatInviteLinkParams) (*ChatInviteLink, error) {
+	tag := "CreateChatInviteLink"
+	form := url.Values{
+		"chat_id":              []string{params.ChatID},
+		"creates_join_request": []string{strconv.FormatBool(params.CreatesJoinRequest)},
+	}
+	if params.Name != "" {
+		form.Set("name", params.Name)
+	}
+	if params.ExpireDate != 0 {
+		form.Set("expire_date", strconv.FormatInt(params.ExpireDate, 10))
+	}
+	if params.MemberLimit != 0 {
+		form.Set("member_limit", strconv.Itoa(params.MemberLimit))
+	}
+
+	var link ChatInviteLink
+	err := bot.call(urlCreateChatInviteLink, "", formBody(form), &link)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return &link, nil
+}
@@ -0,0 +1,47 @@
+package dotg
+
+import (
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"testing"
+)
+
+func TestTGBot_GetChatAdministrators(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	srv.Handle("getChatAdministrators", func(call *dotgtest.Call) (interface{}, *dotgtest.APIError) {
+		return []*ChatMember{
+			{Status: MemberCreator, User: &User{ID: 1, FirstName: "A"}},
+			{Status: MemberAdministrator, User: &User{ID: 2, FirstName: "B"}, CanRestrictMembers: true},
+		}, nil
+	})
+
+	members, err := bot.GetChatAdministrators("-100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].Status != MemberCreator || !members[1].CanRestrictMembers ||
+		members[1].User.ID != 2 {
+		t.Errorf("管理员信息不符：%+v", members)
+	}
+}
+
+func TestTGBot_BanChatMemberErrors(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	bot.SetRateLimiter(nil)
+	srv.Fail("banChatMember", 1, &dotgtest.APIError{Code: 400,
+		Description: "Bad Request: not enough rights to restrict/unrestrict chat member"})
+
+	err := bot.BanChatMember(&BanChatMemberParams{ChatID: "-100", UserID: 2})
+	if !IsPermissionError(err) || IsNetworkError(err) {
+		t.Errorf("应为权限错误，实际为 %v", err)
+	}
+	if calls := srv.CallsOf("banChatMember"); len(calls) != 1 || calls[0].Form.Get("user_id") != "2" {
+		t.Errorf("请求不符：%v", calls)
+	}
+
+	// 无法连接时为网络错误
+	bot.SetAddr("http://127.0.0.1:1")
+	err = bot.BanChatMember(&BanChatMemberParams{ChatID: "-100", UserID: 2})
+	if !IsNetworkError(err) || IsPermissionError(err) {
+		t.Errorf("应为网络错误，实际为 %v", err)
+	}
+}
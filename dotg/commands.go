@@ -0,0 +1,189 @@
+package dotg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// 设置机器人的命令菜单、名称、简介，以替代在 BotFather 中手动设置
+
+const (
+	urlSetMyCommands         = "%s/%s/setMyCommands"
+	urlGetMyCommands         = "%s/%s/getMyCommands"
+	urlDeleteMyCommands      = "%s/%s/deleteMyCommands"
+	urlSetMyName             = "%s/%s/setMyName"
+	urlSetMyDescription      = "%s/%s/setMyDescription"
+	urlSetMyShortDescription = "%s/%s/setMyShortDescription"
+)
+
+// BotCommand 机器人的命令
+type BotCommand struct {
+	// 命令，不含"/"（1-32 个字符，仅小写字母、数字、下划线）
+	Command string `json:"command"`
+	// 描述（1-256 个字符）
+	Description string `json:"description"`
+}
+
+// BotCommandScope 命令的适用范围
+type BotCommandScope struct {
+	// 范围的类型。可选 ScopeDefault、ScopeAllPrivateChats 等
+	Type string `json:"type"`
+	// 仅 ScopeChat、ScopeChatAdministrators、ScopeChatMember 需要
+	ChatID string `json:"chat_id,omitempty"`
+	// 仅 ScopeChatMember 需要
+	UserID int64 `json:"user_id,omitempty"`
+}
+
+// 命令适用范围的类型
+const (
+	ScopeDefault               = "default"
+	ScopeAllPrivateChats       = "all_private_chats"
+	ScopeAllGroupChats         = "all_group_chats"
+	ScopeAllChatAdministrators = "all_chat_administrators"
+	ScopeChat                  = "chat"
+	ScopeChatAdministrators    = "chat_administrators"
+	ScopeChatMember            = "chat_member"
+)
+
+// CommandMenu 某一范围、语言下的命令菜单，用于 SyncMyCommands
+type CommandMenu struct {
+	// 适用范围，为 nil 时为 ScopeDefault
+	Scope *BotCommandScope
+	// 适用语言（ISO 639-1，如"zh"），为空""时适用于所有未单独设置的语言
+	Lang string
+	// 命令。为空时删除该范围、语言下的命令
+	Commands []BotCommand
+}
+
+// SetMyCommands 设置命令菜单
+//
+// scope 适用范围，为 nil 时为 ScopeDefault；lang 适用语言，为空""时适用于所有未单独设置的语言
+func (bot *TGBot) SetMyCommands(commands []BotCommand, scope *BotCommandScope, lang string) error {
+	tag := "SetMyCommands"
+	form, err := scopeForm(scope, lang)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	bs, err := json.Marshal(commands)
+	if err != nil {
+		return fmt.Errorf("[%s]序列化命令出错：%w", tag, err)
+	}
+	form.Set("commands", string(bs))
+
+	err = bot.call(urlSetMyCommands, "", formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// GetMyCommands 获取命令菜单
+func (bot *TGBot) GetMyCommands(scope *BotCommandScope, lang string) ([]BotCommand, error) {
+	tag := "GetMyCommands"
+	form, err := scopeForm(scope, lang)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	var commands []BotCommand
+	err = bot.call(urlGetMyCommands, "", formBody(form), &commands)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return commands, nil
+}
+
+// DeleteMyCommands 删除命令菜单。之后将显示更宽泛的范围、语言下的命令
+func (bot *TGBot) DeleteMyCommands(scope *BotCommandScope, lang string) error {
+	tag := "DeleteMyCommands"
+	form, err := scopeForm(scope, lang)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	err = bot.call(urlDeleteMyCommands, "", formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// SyncMyCommands 按声明同步命令菜单。只在与当前的命令不同时才设置，可在每次部署、启动时调用
+func (bot *TGBot) SyncMyCommands(menus []*CommandMenu) error {
+	tag := "SyncMyCommands"
+	for _, menu := range menus {
+		current, err := bot.GetMyCommands(menu.Scope, menu.Lang)
+		if err != nil {
+			return fmt.Errorf("[%s]%w", tag, err)
+		}
+
+		if len(menu.Commands) == 0 {
+			if len(current) != 0 {
+				err = bot.DeleteMyCommands(menu.Scope, menu.Lang)
+			}
+		} else if !reflect.DeepEqual(current, menu.Commands) {
+			err = bot.SetMyCommands(menu.Commands, menu.Scope, menu.Lang)
+		}
+		if err != nil {
+			return fmt.Errorf("[%s]%w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// SetMyName 设置机器人的名称（0-64 个字符）。为空""时删除该语言的名称
+func (bot *TGBot) SetMyName(name string, lang string) error {
+	return bot.setMyProfile(urlSetMyName, "SetMyName", "name", name, lang)
+}
+
+// SetMyDescription 设置机器人的简介（0-512 个字符），显示在与机器人的空白会话中。为空""时删除该语言的简介
+func (bot *TGBot) SetMyDescription(description string, lang string) error {
+	return bot.setMyProfile(urlSetMyDescription, "SetMyDescription", "description", description, lang)
+}
+
+// SetMyShortDescription 设置机器人的短简介（0-120 个字符），显示在机器人的资料页、分享链接中。为空""时删除该语言的短简介
+func (bot *TGBot) SetMyShortDescription(description string, lang string) error {
+	return bot.setMyProfile(urlSetMyShortDescription, "SetMyShortDescription", "short_description",
+		description, lang)
+}
+
+// 设置机器人的资料
+func (bot *TGBot) setMyProfile(u string, tag string, field string, value string, lang string) error {
+	form := url.Values{
+		field: []string{value},
+	}
+	if lang != "" {
+		form.Set("language_code", lang)
+	}
+
+	err := bot.call(u, "", formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
+
+// 生成命令适用范围、语言的表单
+func scopeForm(scope *BotCommandScope, lang string) (url.Values, error) {
+	form := url.Values{}
+	if scope != nil {
+		bs, err := json.Marshal(scope)
+		if err != nil {
+			return nil, fmt.Errorf("序列化命令适用范围出错：%w", err)
+		}
+		form.Set("scope", string(bs))
+	}
+	if lang != "" {
+		form.Set("language_code", lang)
+	}
+
+	return form, nil
+}
@@ -0,0 +1,38 @@
+package dotg
+
+import (
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"strings"
+	"testing"
+)
+
+func TestTGBot_SyncMyCommands(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	// 私聊中的命令与声明的相同，其它范围为空
+	srv.Handle("getMyCommands", func(call *dotgtest.Call) (interface{}, *dotgtest.APIError) {
+		if strings.Contains(call.Form.Get("scope"), ScopeAllPrivateChats) {
+			return []BotCommand{{Command: "start", Description: "开始"}}, nil
+		}
+		return []BotCommand{}, nil
+	})
+
+	commands := []BotCommand{{Command: "start", Description: "开始"}}
+	err := bot.SyncMyCommands([]*CommandMenu{
+		{Scope: &BotCommandScope{Type: ScopeAllPrivateChats}, Commands: commands},
+		{Lang: "zh", Commands: commands},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	methods := make([]string, 0)
+	for _, call := range srv.Calls() {
+		methods = append(methods, call.Method)
+	}
+	if strings.Join(methods, ",") != "getMyCommands,getMyCommands,setMyCommands" {
+		t.Errorf("调用不符：%v", methods)
+	}
+	if srv.CallsOf("setMyCommands")[0].Form.Get("language_code") != "zh" {
+		t.Errorf("设置的语言不符")
+	}
+}
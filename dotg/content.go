@@ -0,0 +1,296 @@
+package dotg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// 发送投票、位置、联系人、骰子、贴纸等消息
+
+const (
+	urlSendPoll     = "%s/%s/sendPoll"
+	urlStopPoll     = "%s/%s/stopPoll"
+	urlSendLocation = "%s/%s/sendLocation"
+	urlSendVenue    = "%s/%s/sendVenue"
+	urlSendContact  = "%s/%s/sendContact"
+	urlSendDice     = "%s/%s/sendDice"
+	urlSendSticker  = "%s/%s/sendSticker"
+)
+
+// SendPollParams 发送投票的参数
+type SendPollParams struct {
+	ChatID string
+	// 问题（1-300 个字符）
+	Question string
+	// 选项（2-10 个，每个 1-100 个字符）
+	Options []string
+
+	// 是否匿名。注意默认为 false，即非匿名，可通过 GetUpdates 收到 PollAnswer
+	IsAnonymous bool
+	// 投票的类型。可选 PollRegular、PollQuiz，为空""时为 PollRegular
+	Type string
+	// 是否可多选。测验不能多选
+	AllowsMultipleAnswers bool
+
+	// 测验的正确选项，从 0 开始。测验必填
+	CorrectOptionID int
+	// 测验答错或点击灯泡图标时显示的解释（0-200 个字符）
+	Explanation          string
+	ExplanationParseMode string
+
+	// 创建后多少秒自动结束（5-600）。不能与 CloseDate 同时设置
+	OpenPeriod int
+	// 自动结束的时刻（Unix 时间戳，秒）
+	CloseDate int64
+}
+
+// VenueParams 发送地点的参数
+type VenueParams struct {
+	ChatID    string
+	Latitude  float64
+	Longitude float64
+	// 地点的名称
+	Title string
+	// 地点的地址
+	Address string
+
+	// 可选，Foursquare、Google Places 中的 ID 及类型
+	FoursquareID    string
+	FoursquareType  string
+	GooglePlaceID   string
+	GooglePlaceType string
+}
+
+// SendPoll 发送投票或测验
+//
+// opts 发送选项，可为 nil
+func (bot *TGBot) SendPoll(params *SendPollParams, opts *SendOptions) (*Message, error) {
+	tag := "SendPoll"
+	bs, err := json.Marshal(params.Options)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]序列化选项出错：%w", tag, err)
+	}
+
+	form := url.Values{
+		"chat_id":                 []string{params.ChatID},
+		"question":                []string{params.Question},
+		"options":                 []string{string(bs)},
+		"is_anonymous":            []string{strconv.FormatBool(params.IsAnonymous)},
+		"allows_multiple_answers": []string{strconv.FormatBool(params.AllowsMultipleAnswers)},
+	}
+	if params.Type != "" {
+		form.Set("type", params.Type)
+	}
+	if params.Type == PollQuiz {
+		form.Set("correct_option_id", strconv.Itoa(params.CorrectOptionID))
+	}
+	if params.Explanation != "" {
+		form.Set("explanation", params.Explanation)
+	}
+	if params.ExplanationParseMode != "" {
+		form.Set("explanation_parse_mode", params.ExplanationParseMode)
+	}
+	if params.OpenPeriod != 0 {
+		form.Set("open_period", strconv.Itoa(params.OpenPeriod))
+	}
+	if params.CloseDate != 0 {
+		form.Set("close_date", strconv.FormatInt(params.CloseDate, 10))
+	}
+
+	err = opts.apply(urlSendPoll, form)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	msg, err := bot.Send(urlSendPoll, params.ChatID, formBody(form))
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return msg, nil
+}
+
+// StopPoll 结束机器人发送的投票，返回最终的投票结果
+func (bot *TGBot) StopPoll(chatID string, messageID int64) (*Poll, error) {
+	tag := "StopPoll"
+	form := url.Values{
+		"chat_id":    []string{chatID},
+		"message_id": []string{strconv.FormatInt(messageID, 10)},
+	}
+
+	var poll Poll
+	err := bot.call(urlStopPoll, chatID, formBody(form), &poll)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return &poll, nil
+}
+
+// SendLocation 发送位置
+//
+// livePeriod 实时位置的更新时长（60-86400 秒）。为 0 时发送静态位置
+//
+// opts 发送选项，可为 nil
+func (bot *TGBot) SendLocation(chatID string, latitude float64, longitude float64,
+	livePeriod int, opts *SendOptions) (*Message, error) {
+	tag := "SendLocation"
+	form := url.Values{
+		"chat_id":   []string{chatID},
+		"latitude":  []string{formatFloat(latitude)},
+		"longitude": []string{formatFloat(longitude)},
+	}
+	if livePeriod != 0 {
+		form.Set("live_period", strconv.Itoa(livePeriod))
+	}
+
+	err := opts.apply(urlSendLocation, form)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	msg, err := bot.Send(urlSendLocation, chatID, formBody(form))
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return msg, nil
+}
+
+// SendVenue 发送地点
+//
+// opts 发送选项，可为 nil
+func (bot *TGBot) SendVenue(params *VenueParams, opts *SendOptions) (*Message, error) {
+	tag := "SendVenue"
+	form := url.Values{
+		"chat_id":   []string{params.ChatID},
+		"latitude":  []string{formatFloat(params.Latitude)},
+		"longitude": []string{formatFloat(params.Longitude)},
+		"title":     []string{params.Title},
+		"address":   []string{params.Address},
+	}
+	optional := map[string]string{
+		"foursquare_id":     params.FoursquareID,
+		"foursquare_type":   params.FoursquareType,
+		"google_place_id":   params.GooglePlaceID,
+		"google_place_type": params.GooglePlaceType,
+	}
+	for k, v := range optional {
+		if v != "" {
+			form.Set(k, v)
+		}
+	}
+
+	err := opts.apply(urlSendVenue, form)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	msg, err := bot.Send(urlSendVenue, params.ChatID, formBody(form))
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return msg, nil
+}
+
+// SendContact 发送联系人
+//
+// lastName 可为空""
+//
+// opts 发送选项，可为 nil
+func (bot *TGBot) SendContact(chatID string, phoneNumber string, firstName string,
+	lastName string, opts *SendOptions) (*Message, error) {
+	tag := "SendContact"
+	form := url.Values{
+		"chat_id":      []string{chatID},
+		"phone_number": []string{phoneNumber},
+		"first_name":   []string{firstName},
+	}
+	if lastName != "" {
+		form.Set("last_name", lastName)
+	}
+
+	err := opts.apply(urlSendContact, form)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	msg, err := bot.Send(urlSendContact, chatID, formBody(form))
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return msg, nil
+}
+
+// SendDice 发送随机骰子。点数可通过结果中的 TGMessage.Dice 获取
+//
+// emoji 骰子的表情，如 DiceDice、DiceSlot。为空""时为 DiceDice
+//
+// opts 发送选项，可为 nil
+func (bot *TGBot) SendDice(chatID string, emoji string, opts *SendOptions) (*Message, error) {
+	tag := "SendDice"
+	form := url.Values{
+		"chat_id": []string{chatID},
+	}
+	if emoji != "" {
+		form.Set("emoji", emoji)
+	}
+
+	err := opts.apply(urlSendDice, form)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	msg, err := bot.Send(urlSendDice, chatID, formBody(form))
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return msg, nil
+}
+
+// SendSticker 发送贴纸
+//
+// sticker 可为 string（已存在的 file_id、URL，或本地服务模式下"file://"开头的路径）、
+// Opener、io.Reader（.webp、.tgs、.webm 文件的数据）
+//
+// opts 发送选项，可为 nil
+func (bot *TGBot) SendSticker(chatID string, sticker interface{}, opts *SendOptions) (*Message, error) {
+	tag := "SendSticker"
+	form := url.Values{
+		"chat_id": []string{chatID},
+	}
+
+	err := opts.apply(urlSendSticker, form)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	var genBody BodyFunc
+	switch s := sticker.(type) {
+	case string:
+		form.Set("sticker", s)
+		genBody = formBody(form)
+	default:
+		if !isUpload(sticker) {
+			return nil, fmt.Errorf("[%s]不支持的贴纸数据类型：%T", tag, sticker)
+		}
+		genBody = multipartBody(form, []formFile{{field: "sticker", name: "sticker.webp", src: sticker}})
+	}
+
+	msg, err := bot.Send(urlSendSticker, chatID, genBody)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return msg, nil
+}
+
+// 格式化经纬度，不丢失精度
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
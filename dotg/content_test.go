@@ -0,0 +1,39 @@
+package dotg
+
+import (
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"testing"
+)
+
+func TestTGBot_SendPoll(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	srv.Handle("sendPoll", func(call *dotgtest.Call) (interface{}, *dotgtest.APIError) {
+		return &TGMessage{MessageID: 10, Chat: &Chat{ID: 1}, Poll: &Poll{ID: "p1", Type: PollQuiz,
+			Question: call.Form.Get("question"), Options: []*PollOption{{Text: "A"}, {Text: "B"}}}}, nil
+	})
+
+	msg, err := bot.SendPoll(&SendPollParams{
+		ChatID:          "1",
+		Question:        "Q",
+		Options:         []string{"A", "B"},
+		Type:            PollQuiz,
+		CorrectOptionID: 1,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := srv.CallsOf("sendPoll")[0].Form
+	if form.Get("type") != PollQuiz || form.Get("correct_option_id") != "1" ||
+		form.Get("options") != `["A","B"]` || form.Get("is_anonymous") != "false" {
+		t.Errorf("表单不符：%v", form)
+	}
+
+	var m TGMessage
+	if err = msg.ParseResult(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.MessageID != 10 || m.Poll == nil || m.Poll.Question != "Q" || len(m.Poll.Options) != 2 {
+		t.Errorf("结果不符：%+v", m)
+	}
+}
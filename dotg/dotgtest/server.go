@@ -0,0 +1,445 @@
+// Package dotgtest 进程内的 TG Bot API 模拟服务，用于离线测试 dotg.TGBot
+//
+// 记录收到的调用，校验 multipart、媒体集的数据，可模拟速率限制(429)、失败响应，以及本地服务的"file://"语义
+//
+// srv := dotgtest.NewServer()
+// defer srv.Close()
+// bot := dotg.NewTGBot(dotgtest.Token)
+// bot.SetAddr(srv.URL)
+package dotgtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token 默认接受的机器人 token，不含"bot"前缀
+const Token = "123:test"
+
+// Call 收到的一次调用
+type Call struct {
+	// 调用的方法，如"sendMessage"
+	Method string
+	// 表单字段，包括 multipart 中的非文件字段
+	Form url.Values
+	// multipart 中的文件，键为字段名
+	Files map[string]*File
+}
+
+// File multipart 中的文件，或通过 AddFile 添加的可下载文件
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// APIError 模拟的失败响应
+type APIError struct {
+	Code        int
+	Description string
+	// 为 429 时需等待的秒数
+	RetryAfter int
+}
+
+// HandlerFunc 自定义方法的处理。返回成功时的 result，或者失败时的错误
+type HandlerFunc func(call *Call) (interface{}, *APIError)
+
+// Server 模拟的 TG Bot API 服务
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	token    string
+	local    bool
+	calls    []*Call
+	handlers map[string]HandlerFunc
+	// 各方法待返回的失败响应，依次返回
+	failures map[string][]*APIError
+	// 可下载的文件，键为 file_id
+	files map[string]*File
+	// 本地模式下，保存可下载文件的目录
+	dir       string
+	messageID int64
+}
+
+// NewServer 创建并启动模拟服务。用完需调用 Close()
+func NewServer() *Server {
+	s := &Server{
+		token:    Token,
+		handlers: make(map[string]HandlerFunc),
+		failures: make(map[string][]*APIError),
+		files:    make(map[string]*File),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// Close 关闭服务，并删除本地模式下保存的文件
+func (s *Server) Close() {
+	s.Server.Close()
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
+
+// SetToken 设置接受的机器人 token（不含"bot"前缀），其它 token 将返回 401
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = token
+}
+
+// SetLocal 设置是否模拟以 --local 模式运行的本地服务
+//
+// 本地模式下，媒体可为"file://"开头的本机路径（文件需存在），getFile 返回本机的绝对路径；
+// 否则与 TG 直连一样，不接受"file://"
+func (s *Server) SetLocal(local bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.local = local
+}
+
+// Handle 自定义方法的处理，覆盖默认的处理
+func (s *Server) Handle(method string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[method] = handler
+}
+
+// Fail 使接下来 times 次调用 method 时，返回失败响应
+func (s *Server) Fail(method string, times int, err *APIError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < times; i++ {
+		s.failures[method] = append(s.failures[method], err)
+	}
+}
+
+// RateLimit 使接下来 times 次调用 method 时，返回速率限制(429)，要求等待 retryAfter 秒
+func (s *Server) RateLimit(method string, times int, retryAfter int) {
+	s.Fail(method, times, &APIError{
+		Code:        429,
+		Description: fmt.Sprintf("Too Many Requests: retry after %d", retryAfter),
+		RetryAfter:  retryAfter,
+	})
+}
+
+// AddFile 添加可通过 getFile、下载地址获取的文件
+func (s *Server) AddFile(fileID string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[fileID] = &File{Name: fileID, Content: content}
+}
+
+// Calls 获取收到的所有调用
+func (s *Server) Calls() []*Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Call(nil), s.calls...)
+}
+
+// CallsOf 获取收到的对 method 的调用
+func (s *Server) CallsOf(method string) []*Call {
+	calls := make([]*Call, 0)
+	for _, c := range s.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset 清空记录的调用、待返回的失败响应
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = nil
+	s.failures = make(map[string][]*APIError)
+}
+
+// 处理请求
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	// 下载文件：/file/bot<token>/<file_path>
+	if strings.HasPrefix(r.URL.Path, "/file/") {
+		s.serveFile(w, r)
+		return
+	}
+
+	// 调用方法：/bot<token>/<method>
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] != "bot"+s.currentToken() {
+		writeResp(w, nil, &APIError{Code: 401, Description: "Unauthorized"})
+		return
+	}
+
+	call, err := parseCall(parts[1], r)
+	if err != nil {
+		writeResp(w, nil, &APIError{Code: 400, Description: "Bad Request: " + err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	var failure *APIError
+	if list := s.failures[call.Method]; len(list) != 0 {
+		failure, s.failures[call.Method] = list[0], list[1:]
+	}
+	handler := s.handlers[call.Method]
+	s.mu.Unlock()
+
+	if failure != nil {
+		writeResp(w, nil, failure)
+		return
+	}
+	var result interface{}
+	var apiErr *APIError
+	if handler != nil {
+		result, apiErr = handler(call)
+	} else {
+		result, apiErr = s.defaultResult(call)
+	}
+	writeResp(w, result, apiErr)
+}
+
+// 默认的处理
+func (s *Server) defaultResult(call *Call) (interface{}, *APIError) {
+	switch {
+	case call.Method == "sendMediaGroup":
+		medias, apiErr := s.checkMediaGroup(call)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		msgs := make([]interface{}, len(medias))
+		for i := range medias {
+			msgs[i] = s.newMessage(call)
+		}
+		return msgs, nil
+
+	case strings.HasPrefix(call.Method, "send"):
+		if apiErr := s.checkAttachments(call); apiErr != nil {
+			return nil, apiErr
+		}
+		return s.newMessage(call), nil
+
+	case call.Method == "getFile":
+		return s.getFile(call.Form.Get("file_id"))
+
+	case call.Method == "getUpdates" || call.Method == "getChatAdministrators" ||
+		call.Method == "getMyCommands":
+		return []interface{}{}, nil
+
+	default:
+		return true, nil
+	}
+}
+
+// 生成发送成功的消息
+func (s *Server) newMessage(call *Call) map[string]interface{} {
+	s.mu.Lock()
+	s.messageID++
+	id := s.messageID
+	s.mu.Unlock()
+
+	msg := map[string]interface{}{
+		"message_id": id,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]interface{}{"id": call.Form.Get("chat_id")},
+	}
+	if text := call.Form.Get("text"); text != "" {
+		msg["text"] = text
+	}
+	return msg
+}
+
+// 校验媒体集
+func (s *Server) checkMediaGroup(call *Call) ([]map[string]interface{}, *APIError) {
+	var medias []map[string]interface{}
+	err := json.Unmarshal([]byte(call.Form.Get("media")), &medias)
+	if err != nil {
+		return nil, &APIError{Code: 400, Description: "Bad Request: can't parse media JSON object"}
+	}
+	if len(medias) == 0 || len(medias) > 10 {
+		return nil, &APIError{Code: 400, Description: "Bad Request: wrong number of media"}
+	}
+
+	for _, m := range medias {
+		for _, key := range []string{"media", "thumbnail"} {
+			v, _ := m[key].(string)
+			if v == "" && key == "thumbnail" {
+				continue
+			}
+			if apiErr := s.checkInputFile(call, v); apiErr != nil {
+				return nil, apiErr
+			}
+		}
+	}
+
+	return medias, nil
+}
+
+// 校验其它发送方法中以"attach://"、"file://"指定的文件
+func (s *Server) checkAttachments(call *Call) *APIError {
+	for _, values := range call.Form {
+		for _, v := range values {
+			if strings.HasPrefix(v, "attach://") || strings.HasPrefix(v, "file://") {
+				if apiErr := s.checkInputFile(call, v); apiErr != nil {
+					return apiErr
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// 校验文件的来源
+func (s *Server) checkInputFile(call *Call, v string) *APIError {
+	switch {
+	case strings.HasPrefix(v, "attach://"):
+		if _, ok := call.Files[strings.TrimPrefix(v, "attach://")]; !ok {
+			return &APIError{Code: 400, Description: "Bad Request: file must be non-empty: " + v}
+		}
+	case strings.HasPrefix(v, "file://"):
+		s.mu.Lock()
+		local := s.local
+		s.mu.Unlock()
+		if !local {
+			return &APIError{Code: 400, Description: "Bad Request: wrong HTTP URL specified: " + v}
+		}
+		if _, err := os.Stat(strings.TrimPrefix(v, "file://")); err != nil {
+			return &APIError{Code: 400, Description: "Bad Request: file not found: " + v}
+		}
+	case v == "":
+		return &APIError{Code: 400, Description: "Bad Request: there is no media in the request"}
+	}
+	return nil
+}
+
+// 获取文件信息。本地模式下，将文件保存到本机，返回绝对路径
+func (s *Server) getFile(fileID string) (interface{}, *APIError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[fileID]
+	if !ok {
+		return nil, &APIError{Code: 400, Description: "Bad Request: invalid file_id"}
+	}
+
+	result := map[string]interface{}{
+		"file_id":        fileID,
+		"file_unique_id": fileID,
+		"file_size":      len(f.Content),
+		"file_path":      "documents/" + fileID,
+	}
+
+	if s.local {
+		if s.dir == "" {
+			dir, err := os.MkdirTemp("", "dotgtest_")
+			if err != nil {
+				return nil, &APIError{Code: 500, Description: err.Error()}
+			}
+			s.dir = dir
+		}
+		p := filepath.Join(s.dir, fileID)
+		if err := os.WriteFile(p, f.Content, 0644); err != nil {
+			return nil, &APIError{Code: 500, Description: err.Error()}
+		}
+		result["file_path"] = p
+	}
+
+	return result, nil
+}
+
+// 下载文件
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	prefix := "/file/bot" + s.currentToken() + "/documents/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	f, ok := s.files[strings.TrimPrefix(r.URL.Path, prefix)]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Write(f.Content)
+}
+
+func (s *Server) currentToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.token
+}
+
+// 解析请求为调用
+func parseCall(method string, r *http.Request) (*Call, error) {
+	call := &Call{Method: method, Form: url.Values{}, Files: make(map[string]*File)}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, fmt.Errorf("can't parse multipart: %w", err)
+		}
+		for k, vs := range r.MultipartForm.Value {
+			call.Form[k] = vs
+		}
+		for field, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			bs, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			call.Files[field] = &File{Name: headers[0].Filename, Content: bs}
+		}
+	default:
+		if err := r.ParseForm(); err != nil {
+			return nil, fmt.Errorf("can't parse form: %w", err)
+		}
+		call.Form = r.Form
+	}
+
+	return call, nil
+}
+
+// 写入响应
+func writeResp(w http.ResponseWriter, result interface{}, apiErr *APIError) {
+	resp := map[string]interface{}{"ok": apiErr == nil}
+	if apiErr != nil {
+		resp["error_code"] = apiErr.Code
+		resp["description"] = apiErr.Description
+		if apiErr.RetryAfter != 0 {
+			resp["parameters"] = map[string]interface{}{"retry_after": apiErr.RetryAfter}
+		}
+	} else {
+		resp["result"] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,54 @@
+package dotgtest_test
+
+import (
+	"errors"
+	"github.com/donething/utils-go/dotg"
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_FileScheme(t *testing.T) {
+	srv := dotgtest.NewServer()
+	defer srv.Close()
+
+	bot := dotg.NewTGBot(dotgtest.Token)
+	bot.SetAddr(srv.URL)
+	bot.SetRateLimiter(nil)
+
+	path := filepath.Join(t.TempDir(), "a.mp4")
+	if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	medias := []*dotg.InputMedia{{Type: dotg.TypeVideo, Media: "file://" + path}}
+
+	// 非本地模式不接受"file://"
+	var apiErr *dotg.APIError
+	if _, err := bot.SendMediaGroup("1", medias, nil); !errors.As(err, &apiErr) || apiErr.Code != 400 {
+		t.Errorf("应返回 400 错误，实际为 %v", err)
+	}
+
+	// 本地模式下，文件需存在
+	srv.SetLocal(true)
+	if _, err := bot.SendMediaGroup("1", medias, nil); err != nil {
+		t.Fatal(err)
+	}
+	medias = []*dotg.InputMedia{{Type: dotg.TypeVideo, Media: "file://" + path + ".none"}}
+	if _, err := bot.SendMediaGroup("1", medias, nil); !errors.As(err, &apiErr) || apiErr.Code != 400 {
+		t.Errorf("应返回 400 错误，实际为 %v", err)
+	}
+}
+
+func TestServer_Token(t *testing.T) {
+	srv := dotgtest.NewServer()
+	defer srv.Close()
+
+	bot := dotg.NewTGBot("wrong")
+	bot.SetAddr(srv.URL)
+
+	var apiErr *dotg.APIError
+	if _, err := bot.SendMessage("1", "test", nil); !errors.As(err, &apiErr) || apiErr.Code != 401 {
+		t.Errorf("应返回 401 错误，实际为 %v", err)
+	}
+}
@@ -48,3 +48,256 @@ const (
 	ParseMK2  = "MarkdownV2"
 	ParseHTML = "HTML"
 )
+
+// User TG 用户或机器人
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// Chat 会话
+type Chat struct {
+	ID int64 `json:"id"`
+	// 会话的类型。可选 ChatPrivate、ChatGroup、ChatSupergroup、ChatChannel
+	Type      string `json:"type"`
+	Title     string `json:"title,omitempty"`
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	// 是否开启了话题（论坛）
+	IsForum bool `json:"is_forum,omitempty"`
+
+	// 以下仅 getChat 返回
+	Description string           `json:"description,omitempty"`
+	InviteLink  string           `json:"invite_link,omitempty"`
+	Permissions *ChatPermissions `json:"permissions,omitempty"`
+	// 慢速模式下，每个用户两次发言的最小间隔（秒）
+	SlowModeDelay int `json:"slow_mode_delay,omitempty"`
+}
+
+// 会话的类型
+const (
+	ChatPrivate    = "private"
+	ChatGroup      = "group"
+	ChatSupergroup = "supergroup"
+	ChatChannel    = "channel"
+)
+
+// ChatPermissions 群组成员的权限
+type ChatPermissions struct {
+	CanSendMessages       bool `json:"can_send_messages"`
+	CanSendAudios         bool `json:"can_send_audios"`
+	CanSendDocuments      bool `json:"can_send_documents"`
+	CanSendPhotos         bool `json:"can_send_photos"`
+	CanSendVideos         bool `json:"can_send_videos"`
+	CanSendVideoNotes     bool `json:"can_send_video_notes"`
+	CanSendVoiceNotes     bool `json:"can_send_voice_notes"`
+	CanSendPolls          bool `json:"can_send_polls"`
+	CanSendOtherMessages  bool `json:"can_send_other_messages"`
+	CanAddWebPagePreviews bool `json:"can_add_web_page_previews"`
+	CanChangeInfo         bool `json:"can_change_info"`
+	CanInviteUsers        bool `json:"can_invite_users"`
+	CanPinMessages        bool `json:"can_pin_messages"`
+	CanManageTopics       bool `json:"can_manage_topics"`
+}
+
+// ChatMember 群组成员。不同状态的成员拥有的字段不同，未拥有的字段为零值
+type ChatMember struct {
+	// 成员的状态。可选 MemberCreator、MemberAdministrator、MemberMember、MemberRestricted、MemberLeft、MemberKicked
+	Status string `json:"status"`
+	User   *User  `json:"user"`
+
+	// 创建者、管理员
+	IsAnonymous bool   `json:"is_anonymous,omitempty"`
+	CustomTitle string `json:"custom_title,omitempty"`
+
+	// 管理员
+	CanBeEdited         bool `json:"can_be_edited,omitempty"`
+	CanManageChat       bool `json:"can_manage_chat,omitempty"`
+	CanDeleteMessages   bool `json:"can_delete_messages,omitempty"`
+	CanManageVideoChats bool `json:"can_manage_video_chats,omitempty"`
+	CanRestrictMembers  bool `json:"can_restrict_members,omitempty"`
+	CanPromoteMembers   bool `json:"can_promote_members,omitempty"`
+	CanPostMessages     bool `json:"can_post_messages,omitempty"`
+	CanEditMessages     bool `json:"can_edit_messages,omitempty"`
+
+	// 管理员、受限成员
+	CanChangeInfo   bool `json:"can_change_info,omitempty"`
+	CanInviteUsers  bool `json:"can_invite_users,omitempty"`
+	CanPinMessages  bool `json:"can_pin_messages,omitempty"`
+	CanManageTopics bool `json:"can_manage_topics,omitempty"`
+
+	// 受限成员
+	IsMember        bool `json:"is_member,omitempty"`
+	CanSendMessages bool `json:"can_send_messages,omitempty"`
+
+	// 受限、被封禁成员的解除时刻（Unix 时间戳，秒）。为 0 表示永久
+	UntilDate int64 `json:"until_date,omitempty"`
+}
+
+// 群组成员的状态
+const (
+	MemberCreator       = "creator"
+	MemberAdministrator = "administrator"
+	MemberMember        = "member"
+	MemberRestricted    = "restricted"
+	MemberLeft          = "left"
+	MemberKicked        = "kicked"
+)
+
+// ChatInviteLink 邀请链接
+type ChatInviteLink struct {
+	InviteLink         string `json:"invite_link"`
+	Creator            *User  `json:"creator"`
+	CreatesJoinRequest bool   `json:"creates_join_request"`
+	IsPrimary          bool   `json:"is_primary"`
+	IsRevoked          bool   `json:"is_revoked"`
+	Name               string `json:"name,omitempty"`
+	// 过期时刻（Unix 时间戳，秒）
+	ExpireDate int64 `json:"expire_date,omitempty"`
+	// 通过该链接最多可加入的人数
+	MemberLimit int `json:"member_limit,omitempty"`
+}
+
+// TGMessage TG 中的一条消息。只包含常用的字段
+//
+// 注意与发送后返回的响应 Message 区分
+type TGMessage struct {
+	MessageID int64 `json:"message_id"`
+	// 所在话题的 ID，仅话题中的消息有
+	MessageThreadID int64 `json:"message_thread_id,omitempty"`
+	From            *User `json:"from,omitempty"`
+	Chat            *Chat `json:"chat"`
+	// 发送时刻（Unix 时间戳，秒）
+	Date int64 `json:"date"`
+
+	Text     string    `json:"text,omitempty"`
+	Caption  string    `json:"caption,omitempty"`
+	Poll     *Poll     `json:"poll,omitempty"`
+	Dice     *Dice     `json:"dice,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Venue    *Venue    `json:"venue,omitempty"`
+	Contact  *Contact  `json:"contact,omitempty"`
+	Sticker  *Sticker  `json:"sticker,omitempty"`
+}
+
+// Poll 投票
+type Poll struct {
+	ID              string        `json:"id"`
+	Question        string        `json:"question"`
+	Options         []*PollOption `json:"options"`
+	TotalVoterCount int           `json:"total_voter_count"`
+	IsClosed        bool          `json:"is_closed"`
+	IsAnonymous     bool          `json:"is_anonymous"`
+	// 投票的类型。可选 PollRegular、PollQuiz
+	Type                  string `json:"type"`
+	AllowsMultipleAnswers bool   `json:"allows_multiple_answers"`
+	// 测验的正确选项，从 0 开始。仅测验的发起者、已作答的用户可见
+	CorrectOptionID int    `json:"correct_option_id,omitempty"`
+	Explanation     string `json:"explanation,omitempty"`
+	// 创建后多少秒自动结束
+	OpenPeriod int `json:"open_period,omitempty"`
+	// 自动结束的时刻（Unix 时间戳，秒）
+	CloseDate int64 `json:"close_date,omitempty"`
+}
+
+// PollOption 投票的选项
+type PollOption struct {
+	Text       string `json:"text"`
+	VoterCount int    `json:"voter_count"`
+}
+
+// PollAnswer 用户在非匿名投票中的作答
+type PollAnswer struct {
+	PollID string `json:"poll_id"`
+	// 以频道身份匿名作答时，为该频道
+	VoterChat *Chat `json:"voter_chat,omitempty"`
+	User      *User `json:"user,omitempty"`
+	// 所选选项，从 0 开始。撤回作答时为空
+	OptionIDs []int `json:"option_ids"`
+}
+
+// 投票的类型
+const (
+	PollRegular = "regular"
+	PollQuiz    = "quiz"
+)
+
+// Dice 随机骰子
+type Dice struct {
+	Emoji string `json:"emoji"`
+	Value int    `json:"value"`
+}
+
+// 骰子的表情
+const (
+	DiceDice     = "🎲"
+	DiceDart     = "🎯"
+	DiceBasket   = "🏀"
+	DiceFootball = "⚽"
+	DiceBowling  = "🎳"
+	DiceSlot     = "🎰"
+)
+
+// Location 位置
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// 实时位置的更新时长（秒）
+	LivePeriod int `json:"live_period,omitempty"`
+}
+
+// Venue 地点
+type Venue struct {
+	Location *Location `json:"location"`
+	Title    string    `json:"title"`
+	Address  string    `json:"address"`
+}
+
+// Contact 联系人
+type Contact struct {
+	PhoneNumber string `json:"phone_number"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name,omitempty"`
+	UserID      int64  `json:"user_id,omitempty"`
+}
+
+// Sticker 贴纸
+type Sticker struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	Emoji        string `json:"emoji,omitempty"`
+	SetName      string `json:"set_name,omitempty"`
+}
+
+// Update 通过 GetUpdates 获取的更新。每次更新只有一项不为 nil
+type Update struct {
+	UpdateID      int64       `json:"update_id"`
+	Message       *TGMessage  `json:"message,omitempty"`
+	EditedMessage *TGMessage  `json:"edited_message,omitempty"`
+	ChannelPost   *TGMessage  `json:"channel_post,omitempty"`
+	Poll          *Poll       `json:"poll,omitempty"`
+	PollAnswer    *PollAnswer `json:"poll_answer,omitempty"`
+}
+
+// 更新的类型，用于 GetUpdates 的 allowedUpdates
+const (
+	UpdateMessage       = "message"
+	UpdateEditedMessage = "edited_message"
+	UpdateChannelPost   = "channel_post"
+	UpdatePoll          = "poll"
+	UpdatePollAnswer    = "poll_answer"
+)
+
+// ForumTopic 话题（论坛）
+type ForumTopic struct {
+	MessageThreadID int64  `json:"message_thread_id"`
+	Name            string `json:"name"`
+	// 图标的颜色（RGB）
+	IconColor         int    `json:"icon_color"`
+	IconCustomEmojiID string `json:"icon_custom_emoji_id,omitempty"`
+}
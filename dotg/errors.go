@@ -0,0 +1,58 @@
+package dotg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNetwork 网络出错，未能得到 TG 的响应。可用 errors.Is() 判断
+	ErrNetwork = errors.New("网络出错")
+)
+
+// 表示权限不足的错误描述（小写）
+var permissionDescs = []string{
+	"not enough rights",
+	"have no rights",
+	"chat_admin_required",
+	"right_forbidden",
+	"user is an administrator of the chat",
+}
+
+// APIError TG 返回的失败响应。可用 errors.As() 获取
+type APIError struct {
+	Code        int
+	Description string
+	Parameters  *ResponseParameters
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Description)
+}
+
+// IsPermission 是否因权限不足而失败，如机器人被移出群组、不是管理员、缺少某项管理权限
+func (e *APIError) IsPermission() bool {
+	if e.Code == 403 {
+		return true
+	}
+
+	desc := strings.ToLower(e.Description)
+	for _, d := range permissionDescs {
+		if strings.Contains(desc, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPermissionError 错误是否因权限不足
+func IsPermissionError(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.IsPermission()
+}
+
+// IsNetworkError 错误是否因网络出错
+func IsNetworkError(err error) bool {
+	return errors.Is(err, ErrNetwork)
+}
@@ -0,0 +1,150 @@
+package dotg
+
+import (
+	"errors"
+	"fmt"
+	"github.com/donething/utils-go/dohttp"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const (
+	urlGetFile = "%s/%s/getFile"
+	// 下载文件的地址。依次为 addr、token、file_path
+	urlDownloadFile = "%s/file/%s/%s"
+
+	// MaxDownloadSize TG 直连时，机器人可下载的最大文件为 20 MB
+	MaxDownloadSize int64 = 20 * 1024 * 1024
+)
+
+var (
+	// ErrFileTooLarge 文件超过了大小限制
+	ErrFileTooLarge = errors.New("文件超过了大小限制")
+)
+
+// File 可下载的文件信息
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size,omitempty"`
+
+	// 文件的路径。TG 直连时为相对路径，需通过 urlDownloadFile 下载；
+	// 本地服务以 --local 模式运行时，为服务所在机器上的绝对路径
+	FilePath string `json:"file_path,omitempty"`
+}
+
+// ProgressFunc 下载进度的回调。total 未知时为 0
+type ProgressFunc func(done int64, total int64)
+
+// GetFile 根据 file_id 获取文件信息
+func (bot *TGBot) GetFile(fileID string) (*File, error) {
+	tag := "GetFile"
+	form := url.Values{
+		"file_id": []string{fileID},
+	}
+
+	var file File
+	err := bot.call(urlGetFile, "", formBody(form), &file)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return &file, nil
+}
+
+// Download 下载文件，返回文件在本地的路径
+//
+// 连接本地服务且其返回的是本机存在的绝对路径时（--local 模式），直接返回该路径，不会复制到 savePath
+//
+// maxSize 文件的最大字节数，超过时返回 ErrFileTooLarge。为 0 时，TG 直连限制为 MaxDownloadSize，本地服务不限制
+//
+// progress 下载进度的回调，可为 nil
+func (bot *TGBot) Download(fileID string, savePath string, maxSize int64,
+	progress ProgressFunc) (string, error) {
+	tag := "Download"
+	if maxSize <= 0 && !bot.IsLocal() {
+		maxSize = MaxDownloadSize
+	}
+
+	file, err := bot.GetFile(fileID)
+	if err != nil {
+		return "", fmt.Errorf("[%s]%w", tag, err)
+	}
+	if maxSize > 0 && file.FileSize > maxSize {
+		return "", fmt.Errorf("[%s]%d 字节：%w", tag, file.FileSize, ErrFileTooLarge)
+	}
+	if file.FilePath == "" {
+		return "", fmt.Errorf("[%s]未返回文件路径，可能文件过大无法下载", tag)
+	}
+
+	// 本地服务的文件，直接使用
+	if bot.IsLocal() && filepath.IsAbs(file.FilePath) {
+		if _, err := os.Stat(file.FilePath); err == nil {
+			if progress != nil {
+				progress(file.FileSize, file.FileSize)
+			}
+			return file.FilePath, nil
+		}
+	}
+
+	// 下载
+	resp, err := bot.client.Get(fmt.Sprintf(urlDownloadFile, bot.addr, bot.token, file.FilePath), nil)
+	if err != nil {
+		return "", fmt.Errorf("[%s]执行请求出错：%w", tag, err)
+	}
+	defer resp.Body.Close()
+
+	if !dohttp.CheckCode(resp.StatusCode) {
+		return "", fmt.Errorf("[%s]下载失败：%s", tag, resp.Status)
+	}
+
+	total := file.FileSize
+	if total == 0 && resp.ContentLength > 0 {
+		total = resp.ContentLength
+	}
+
+	err = os.MkdirAll(filepath.Dir(savePath), 0755)
+	if err != nil {
+		return "", fmt.Errorf("[%s]创建保存目录出错：%w", tag, err)
+	}
+	out, err := os.Create(savePath)
+	if err != nil {
+		return "", fmt.Errorf("[%s]创建文件出错：%w", tag, err)
+	}
+
+	// 多读 1 字节，以判断是否超出大小限制
+	var body io.Reader = resp.Body
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	n, err := io.Copy(out, &progressReader{r: body, total: total, progress: progress})
+	out.Close()
+	if err == nil && maxSize > 0 && n > maxSize {
+		err = ErrFileTooLarge
+	}
+	if err != nil {
+		os.Remove(savePath)
+		return "", fmt.Errorf("[%s]保存文件出错：%w", tag, err)
+	}
+
+	return savePath, nil
+}
+
+// 读取数据时回调进度
+type progressReader struct {
+	r        io.Reader
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(bs []byte) (int, error) {
+	n, err := p.r.Read(bs)
+	p.done += int64(n)
+	if n > 0 && p.progress != nil {
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+package dotg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTGBot_Download(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	content := "file content"
+	srv.AddFile("abc", []byte(content))
+
+	savePath := filepath.Join(t.TempDir(), "sub", "file.jpg")
+	var done int64
+	p, err := bot.Download("abc", savePath, 0, func(d int64, total int64) { done = d })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != savePath || string(bs) != content || done != int64(len(content)) {
+		t.Errorf("下载的内容不符：%s %s，进度 %d", p, string(bs), done)
+	}
+
+	// 超出大小限制
+	_, err = bot.Download("abc", savePath, 5, nil)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("应返回 ErrFileTooLarge，实际为 %v", err)
+	}
+
+	// 本地服务直接返回本机路径
+	srv.SetLocal(true)
+	p, err = bot.Download("abc", savePath, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == savePath || !filepath.IsAbs(p) {
+		t.Errorf("应返回本地服务的文件路径，实际为 %s", p)
+	}
+}
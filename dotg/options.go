@@ -0,0 +1,89 @@
+package dotg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+// 支持链接预览选项的 API 方法。其它方法会拒绝未知的参数，所以不能发送
+var linkPreviewMethods = map[string]bool{
+	"sendMessage":     true,
+	"editMessageText": true,
+}
+
+// SendOptions 发送消息的通用选项，各发送方法都可传递。为 nil 时使用默认值
+type SendOptions struct {
+	// 发送到话题（论坛）中，为话题的 ID
+	MessageThreadID int64
+	// 回复某条消息
+	ReplyParameters *ReplyParameters
+	// 静默发送，用户收到时没有提示音
+	DisableNotification bool
+	// 禁止转发、保存消息的内容
+	ProtectContent bool
+	// 链接预览的选项。仅发送、编辑文本消息时有效，其它方法将忽略
+	LinkPreviewOptions *LinkPreviewOptions
+}
+
+// ReplyParameters 回复消息的参数
+type ReplyParameters struct {
+	// 被回复的消息的 ID
+	MessageID int64 `json:"message_id"`
+	// 被回复的消息所在的会话，为空""时为当前会话
+	ChatID string `json:"chat_id,omitempty"`
+	// 被回复的消息不存在时，是否仍然发送
+	AllowSendingWithoutReply bool `json:"allow_sending_without_reply,omitempty"`
+	// 引用被回复消息中的部分文本
+	Quote string `json:"quote,omitempty"`
+}
+
+// LinkPreviewOptions 链接预览的选项
+type LinkPreviewOptions struct {
+	// 不显示链接预览
+	IsDisabled bool `json:"is_disabled,omitempty"`
+	// 预览该链接，为空""时预览文本中的第一个链接
+	URL string `json:"url,omitempty"`
+	// 缩小、放大预览中的媒体
+	PreferSmallMedia bool `json:"prefer_small_media,omitempty"`
+	PreferLargeMedia bool `json:"prefer_large_media,omitempty"`
+	// 将预览显示在文本上方
+	ShowAboveText bool `json:"show_above_text,omitempty"`
+}
+
+// 将选项写入表单
+//
+// apiURL 为调用的 API 的 URL（如 urlSendMsg），用于判断该方法支持的选项
+func (opts *SendOptions) apply(apiURL string, form url.Values) error {
+	if opts == nil {
+		return nil
+	}
+
+	if opts.MessageThreadID != 0 {
+		form.Set("message_thread_id", strconv.FormatInt(opts.MessageThreadID, 10))
+	}
+	if opts.DisableNotification {
+		form.Set("disable_notification", "true")
+	}
+	if opts.ProtectContent {
+		form.Set("protect_content", "true")
+	}
+	if opts.ReplyParameters != nil {
+		bs, err := json.Marshal(opts.ReplyParameters)
+		if err != nil {
+			return fmt.Errorf("序列化回复参数出错：%w", err)
+		}
+		form.Set("reply_parameters", string(bs))
+	}
+	if opts.LinkPreviewOptions != nil && linkPreviewMethods[path.Base(apiURL)] {
+		bs, err := json.Marshal(opts.LinkPreviewOptions)
+		if err != nil {
+			return fmt.Errorf("序列化链接预览选项出错：%w", err)
+		}
+		form.Set("link_preview_options", string(bs))
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package dotg
+
+import (
+	"testing"
+)
+
+func TestTGBot_SendMessageOptions(t *testing.T) {
+	bot, srv := newFakeBot(t)
+
+	_, err := bot.SendMessage("-100", "test", &SendOptions{
+		MessageThreadID:     7,
+		ReplyParameters:     &ReplyParameters{MessageID: 3},
+		DisableNotification: true,
+		LinkPreviewOptions:  &LinkPreviewOptions{IsDisabled: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := srv.CallsOf("sendMessage")[0].Form
+	if form.Get("message_thread_id") != "7" || form.Get("disable_notification") != "true" ||
+		form.Get("reply_parameters") != `{"message_id":3}` ||
+		form.Get("link_preview_options") != `{"is_disabled":true}` ||
+		form.Get("protect_content") != "" {
+		t.Errorf("表单不符：%v", form)
+	}
+}
+
+func TestTGBot_SendOptionsLinkPreview(t *testing.T) {
+	bot, srv := newFakeBot(t)
+
+	// 非文本消息不支持链接预览的选项，不应发送
+	opts := &SendOptions{DisableNotification: true, LinkPreviewOptions: &LinkPreviewOptions{IsDisabled: true}}
+	if _, err := bot.SendDice("123", "", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	form := srv.CallsOf("sendDice")[0].Form
+	if form.Get("disable_notification") != "true" || form.Has("link_preview_options") {
+		t.Errorf("表单不符：%v", form)
+	}
+}
@@ -0,0 +1,381 @@
+package dotg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/donething/utils-go/dodb/dobolt"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// QueueStatus 队列中消息的状态
+type QueueStatus string
+
+const (
+	// StatusPending 等待发送
+	StatusPending QueueStatus = "pending"
+	// StatusFailed 发送失败，之后将重试
+	StatusFailed QueueStatus = "failed"
+	// StatusDead 多次发送失败，已放弃（死信），需手动 Retry 或 Remove
+	StatusDead QueueStatus = "dead"
+)
+
+// QueueMaxAttempts 默认的最大发送次数，超过后转为死信
+const QueueMaxAttempts = 5
+
+// QueueMedia 队列中的媒体。以本地文件路径保存，发送时才读取
+type QueueMedia struct {
+	// 媒体的类型。可选 TypeAudio、TypeDocument、TypePhoto、TypeVideo
+	Type string `json:"type"`
+	// 媒体文件的路径
+	Path string `json:"path"`
+	// 缩略图文件的路径，可空
+	Thumbnail string `json:"thumbnail,omitempty"`
+
+	Caption           string `json:"caption,omitempty"`
+	ParseMode         string `json:"parse_mode,omitempty"`
+	SupportsStreaming bool   `json:"supports_streaming,omitempty"`
+	Width             int    `json:"width,omitempty"`
+	Height            int    `json:"height,omitempty"`
+	HasSpoiler        bool   `json:"has_spoiler,omitempty"`
+	Name              string `json:"name,omitempty"`
+}
+
+// QueueItem 队列中的一条消息。Text 与 Medias 二选一
+type QueueItem struct {
+	ID     uint64 `json:"id"`
+	ChatID string `json:"chat_id"`
+
+	// 文本消息
+	Text string `json:"text,omitempty"`
+	// 媒体集
+	Medias []*QueueMedia `json:"medias,omitempty"`
+	// 发送选项
+	Options *SendOptions `json:"options,omitempty"`
+
+	Status QueueStatus `json:"status"`
+	// 已尝试发送的次数
+	Attempts int `json:"attempts"`
+	// 最近一次发送失败的原因
+	LastError string `json:"last_error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// QueueStats 队列的状态统计
+type QueueStats struct {
+	Pending int
+	Failed  int
+	Dead    int
+}
+
+// Queue 持久化的发送队列
+//
+// 消息先保存到数据库，再按会话依次发送，发送成功后才移除，所以程序重启后可继续发送
+//
+// 同一会话的消息严格按入队顺序发送：前一条失败待重试时，不会发送后一条；转为死信后，才继续发送后一条
+type Queue struct {
+	bot    *TGBot
+	db     *dobolt.DoBolt
+	bucket []byte
+
+	// 最大发送次数，超过后转为死信
+	MaxAttempts int
+
+	// 避免同时执行多次 Drain
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewQueue 创建发送队列
+//
+// db 已打开的数据库，name 保存队列的桶名。不同的队列应使用不同的桶名
+func NewQueue(bot *TGBot, db *dobolt.DoBolt, name string) (*Queue, error) {
+	bucket := []byte(name)
+	err := db.Create(bucket)
+	if err != nil {
+		return nil, fmt.Errorf("创建队列的桶出错：%w", err)
+	}
+
+	return &Queue{bot: bot, db: db, bucket: bucket, MaxAttempts: QueueMaxAttempts}, nil
+}
+
+// PushMessage 添加文本消息到队列。返回消息在队列中的 ID
+//
+// opts 发送选项，可为 nil
+func (q *Queue) PushMessage(chatID string, text string, opts *SendOptions) (uint64, error) {
+	return q.push(&QueueItem{ChatID: chatID, Text: text, Options: opts})
+}
+
+// PushMediaGroup 添加媒体集到队列。返回消息在队列中的 ID
+//
+// 媒体文件在发送成功前不能被删除
+//
+// opts 发送选项，可为 nil
+func (q *Queue) PushMediaGroup(chatID string, medias []*QueueMedia, opts *SendOptions) (uint64, error) {
+	return q.push(&QueueItem{ChatID: chatID, Medias: medias, Options: opts})
+}
+
+// 保存消息到队列
+func (q *Queue) push(item *QueueItem) (uint64, error) {
+	item.Status = StatusPending
+	item.CreatedAt = time.Now()
+	item.UpdatedAt = item.CreatedAt
+
+	err := q.db.DB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(q.bucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		item.ID = id
+
+		bs, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		return b.Put(itemKey(id), bs)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("保存消息到队列出错：%w", err)
+	}
+
+	return item.ID, nil
+}
+
+// Drain 发送队列中的消息，直到每个会话都发送完毕，或者遇到待重试的失败
+//
+// 不同会话并发发送，由 TGBot 的速率限制器控制速率
+func (q *Queue) Drain() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items, err := q.List("")
+	if err != nil {
+		return err
+	}
+
+	// 按会话分组，保持入队顺序
+	chats := make(map[string][]*QueueItem)
+	for _, item := range items {
+		if item.Status == StatusDead {
+			continue
+		}
+		chats[item.ChatID] = append(chats[item.ChatID], item)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(chats))
+	for _, list := range chats {
+		wg.Add(1)
+		go func(list []*QueueItem) {
+			defer wg.Done()
+			for _, item := range list {
+				ok, err := q.sendItem(item)
+				if err != nil {
+					errs <- err
+					return
+				}
+				// 待重试时，不再发送该会话后面的消息
+				if !ok && item.Status != StatusDead {
+					return
+				}
+			}
+		}(list)
+	}
+	wg.Wait()
+	close(errs)
+
+	// 只返回数据库的错误，发送失败已记录到消息中。无错误时得到 nil
+	return <-errs
+}
+
+// 发送一条消息，并更新其在数据库中的状态。返回是否发送成功
+func (q *Queue) sendItem(item *QueueItem) (bool, error) {
+	var err error
+	if len(item.Medias) != 0 {
+		_, err = q.bot.SendMediaGroup(item.ChatID, item.inputMedias(), item.Options)
+	} else {
+		_, err = q.bot.SendMessage(item.ChatID, item.Text, item.Options)
+	}
+
+	// 发送成功，从队列中移除
+	if err == nil {
+		return true, q.Remove(item.ID)
+	}
+
+	item.Attempts++
+	item.LastError = err.Error()
+	item.UpdatedAt = time.Now()
+	item.Status = StatusFailed
+	if item.Attempts >= q.MaxAttempts {
+		item.Status = StatusDead
+	}
+
+	return false, q.save(item)
+}
+
+// Start 在新协程中每隔 interval 发送一次队列中的消息。数据库出错时会打印到控制台
+func (q *Queue) Start(interval time.Duration) {
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+
+	go func() {
+		defer close(q.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := q.Drain(); err != nil {
+				fmt.Printf("[Queue]发送队列中的消息出错：%s\n", err)
+			}
+
+			select {
+			case <-q.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止由 Start 开启的发送，等待正在发送的消息完成
+func (q *Queue) Stop() {
+	if q.stop == nil {
+		return
+	}
+
+	close(q.stop)
+	<-q.done
+	q.stop = nil
+}
+
+// Stats 统计队列中各状态的消息数
+func (q *Queue) Stats() (*QueueStats, error) {
+	items, err := q.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	var stats QueueStats
+	for _, item := range items {
+		switch item.Status {
+		case StatusPending:
+			stats.Pending++
+		case StatusFailed:
+			stats.Failed++
+		case StatusDead:
+			stats.Dead++
+		}
+	}
+
+	return &stats, nil
+}
+
+// List 按入队顺序列出指定状态的消息。status 为空""时列出所有消息
+func (q *Queue) List(status QueueStatus) ([]*QueueItem, error) {
+	items := make([]*QueueItem, 0)
+	err := q.db.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(q.bucket).ForEach(func(k, v []byte) error {
+			var item QueueItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return fmt.Errorf("解析消息'%d'出错：%w", binary.BigEndian.Uint64(k), err)
+			}
+			if status == "" || item.Status == status {
+				items = append(items, &item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取队列出错：%w", err)
+	}
+
+	return items, nil
+}
+
+// Retry 将死信重新设为等待发送
+func (q *Queue) Retry(id uint64) error {
+	bs, err := q.db.Get(itemKey(id), q.bucket)
+	if err != nil {
+		return fmt.Errorf("读取消息'%d'出错：%w", id, err)
+	}
+	if bs == nil {
+		return fmt.Errorf("队列中不存在消息'%d'", id)
+	}
+
+	var item QueueItem
+	err = json.Unmarshal(bs, &item)
+	if err != nil {
+		return fmt.Errorf("解析消息'%d'出错：%w", id, err)
+	}
+
+	item.Status = StatusPending
+	item.Attempts = 0
+	item.UpdatedAt = time.Now()
+	return q.save(&item)
+}
+
+// Remove 从队列中移除消息
+func (q *Queue) Remove(id uint64) error {
+	_, err := q.db.Del(itemKey(id), q.bucket)
+	if err != nil {
+		return fmt.Errorf("移除消息'%d'出错：%w", id, err)
+	}
+
+	return nil
+}
+
+// 保存消息的状态
+func (q *Queue) save(item *QueueItem) error {
+	bs, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("序列化消息'%d'出错：%w", item.ID, err)
+	}
+
+	err = q.db.Set(itemKey(item.ID), bs, q.bucket)
+	if err != nil {
+		return fmt.Errorf("保存消息'%d'出错：%w", item.ID, err)
+	}
+
+	return nil
+}
+
+// 生成媒体集。发送时才打开文件
+func (item *QueueItem) inputMedias() []*InputMedia {
+	medias := make([]*InputMedia, len(item.Medias))
+	for i, m := range item.Medias {
+		media := &InputMedia{
+			Type:              m.Type,
+			Media:             OpenPath(m.Path),
+			Caption:           m.Caption,
+			ParseMode:         m.ParseMode,
+			SupportsStreaming: m.SupportsStreaming,
+			Width:             m.Width,
+			Height:            m.Height,
+			HasSpoiler:        m.HasSpoiler,
+			Name:              m.Name,
+		}
+		if media.Name == "" {
+			media.Name = filepath.Base(m.Path)
+		}
+		if m.Thumbnail != "" {
+			media.Thumbnail = OpenPath(m.Thumbnail)
+		}
+		medias[i] = media
+	}
+
+	return medias
+}
+
+// 消息的键。使用大端序的 ID，使键的顺序即为入队顺序
+func itemKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
@@ -0,0 +1,80 @@
+package dotg
+
+import (
+	"github.com/donething/utils-go/dodb/dobolt"
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueue_Drain(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	bot.SetRateLimiter(nil)
+
+	// 第一条消息始终失败
+	srv.Handle("sendMessage", func(call *dotgtest.Call) (interface{}, *dotgtest.APIError) {
+		if call.Form.Get("text") == "bad" {
+			return nil, &dotgtest.APIError{Code: 400, Description: "Bad Request"}
+		}
+		return map[string]interface{}{"message_id": 1}, nil
+	})
+	sent := func() int {
+		n := 0
+		for _, c := range srv.CallsOf("sendMessage") {
+			if c.Form.Get("text") == "good" {
+				n++
+			}
+		}
+		return n
+	}
+
+	db, err := dobolt.Open(filepath.Join(t.TempDir(), "queue.db"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	q, err := NewQueue(bot, db, "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.MaxAttempts = 2
+
+	for _, text := range []string{"bad", "good"} {
+		if _, err = q.PushMessage("123", text, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 第一次失败，待重试，不发送后一条
+	if err = q.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := q.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent() != 0 || stats.Failed != 1 || stats.Pending != 1 {
+		t.Fatalf("第一次发送后，状态不符：%+v，已发送 %d", *stats, sent())
+	}
+
+	// 第二次失败，转为死信，继续发送后一条
+	if err = q.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = q.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent() != 1 || stats.Dead != 1 || stats.Pending != 0 {
+		t.Fatalf("第二次发送后，状态不符：%+v，已发送 %d", *stats, sent())
+	}
+
+	dead, err := q.List(StatusDead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Text != "bad" || dead[0].LastError == "" {
+		t.Errorf("死信不符：%+v", dead)
+	}
+}
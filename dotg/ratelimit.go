@@ -0,0 +1,193 @@
+package dotg
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter TG 发送消息的速率限制器
+//
+// 按 TG 的建议限制发送速率：同一会话每秒 1 条、所有会话每秒共 30 条、同一群组每分钟 20 条
+//
+// 通过 Reserve 预约发送时刻，按调用的先后顺序排队，而不是各自盲目地等待后重试
+//
+// @see https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+type RateLimiter struct {
+	// 同一会话两次发送的最小间隔
+	chatInterval time.Duration
+	// 所有会话每秒最多发送的条数
+	globalLimit int
+	// 同一群组每分钟最多发送的条数
+	groupLimit int
+
+	mu sync.Mutex
+	// 暂停向所有会话发送，直到该时刻
+	pausedUntil time.Time
+	// 各会话的下次可发送时刻
+	chatNext map[string]time.Time
+	// 所有会话最近已预约的发送时刻
+	globalSent []time.Time
+	// 各群组最近已预约的发送时刻
+	groupSent map[string][]time.Time
+	// 上次清理过期会话记录的时刻
+	prunedAt time.Time
+}
+
+// NewRateLimiter 创建速率限制器
+//
+// perChat 同一会话每秒可发送的条数，perSecond 所有会话每秒共可发送的条数，perGroup 同一群组每分钟可发送的条数
+//
+// 参数小于等于 0 时表示不限制该项
+func NewRateLimiter(perChat int, perSecond int, perGroup int) *RateLimiter {
+	l := &RateLimiter{
+		globalLimit: perSecond,
+		groupLimit:  perGroup,
+		chatNext:    make(map[string]time.Time),
+		groupSent:   make(map[string][]time.Time),
+	}
+	if perChat > 0 {
+		l.chatInterval = time.Second / time.Duration(perChat)
+	}
+
+	return l
+}
+
+// NewDefaultRateLimiter 按 TG 建议的限额创建速率限制器
+func NewDefaultRateLimiter() *RateLimiter {
+	return NewRateLimiter(1, 30, 20)
+}
+
+// Reserve 为发送到 chatID 的消息预约发送时刻，返回还需等待的时长
+//
+// chatID 为空""时，只受所有会话的总限额约束
+func (l *RateLimiter) Reserve(chatID string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.pruneChats(now)
+
+	at := now
+	if l.pausedUntil.After(at) {
+		at = l.pausedUntil
+	}
+	if next, ok := l.chatNext[chatID]; ok && next.After(at) {
+		at = next
+	}
+
+	group := chatID != "" && l.groupLimit > 0 && isGroupChat(chatID)
+	for {
+		// 先满足群组限额，再满足总限额；若后者推迟了时刻，需重新检查前者
+		if group {
+			at = earliest(l.groupSent[chatID], at, time.Minute, l.groupLimit)
+		}
+		t := earliest(l.globalSent, at, time.Second, l.globalLimit)
+		if t.Equal(at) {
+			break
+		}
+		at = t
+	}
+
+	// 记录预约，并移除已不影响之后预约的记录
+	if chatID != "" {
+		l.chatNext[chatID] = at.Add(l.chatInterval)
+	}
+	if l.globalLimit > 0 {
+		l.globalSent = append(prune(l.globalSent, now.Add(-time.Second)), at)
+	}
+	if group {
+		l.groupSent[chatID] = append(prune(l.groupSent[chatID], now.Add(-time.Minute)), at)
+	}
+
+	return at.Sub(now)
+}
+
+// Wait 预约发送时刻，并等待到该时刻
+func (l *RateLimiter) Wait(chatID string) {
+	if d := l.Reserve(chatID); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// Block 收到 TG 的速率限制响应(429)后，在 d 时长内暂停向 chatID 发送
+//
+// chatID 为空""时，暂停向所有会话发送
+func (l *RateLimiter) Block(chatID string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if chatID != "" {
+		if until.After(l.chatNext[chatID]) {
+			l.chatNext[chatID] = until
+		}
+		return
+	}
+
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// 移除已不影响之后预约的会话、群组记录，避免向大量会话发送后占用的内存不断增长
+//
+// 每分钟最多清理一次
+func (l *RateLimiter) pruneChats(now time.Time) {
+	if now.Sub(l.prunedAt) < time.Minute {
+		return
+	}
+	l.prunedAt = now
+
+	for chatID, next := range l.chatNext {
+		if !next.After(now) {
+			delete(l.chatNext, chatID)
+		}
+	}
+	for chatID, sent := range l.groupSent {
+		if sent = prune(sent, now.Add(-time.Minute)); len(sent) == 0 {
+			delete(l.groupSent, chatID)
+		} else {
+			l.groupSent[chatID] = sent
+		}
+	}
+}
+
+// 在 at 及之后，找到使 window 时长内的发送条数不超过 limit 的最早时刻
+//
+// 已预约的时刻可能在 at 前后，所以检查 at 前后各一个窗口
+func earliest(sent []time.Time, at time.Time, window time.Duration, limit int) time.Time {
+	if limit <= 0 {
+		return at
+	}
+
+	for {
+		n := 0
+		for _, t := range sent {
+			if t.After(at.Add(-window)) && t.Before(at.Add(window)) {
+				n++
+			}
+		}
+		if n < limit {
+			return at
+		}
+
+		at = at.Add(window / time.Duration(limit))
+	}
+}
+
+// 移除 before 之前的记录
+func prune(sent []time.Time, before time.Time) []time.Time {
+	kept := sent[:0]
+	for _, t := range sent {
+		if t.After(before) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// 是否为群组、频道。它们的 chat_id 为负数（如"-100123"），用户名形式（如"@channel"）也视为群组
+func isGroupChat(chatID string) bool {
+	return strings.HasPrefix(chatID, "-") || strings.HasPrefix(chatID, "@")
+}
@@ -0,0 +1,86 @@
+package dotg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	l := NewRateLimiter(1, 30, 20)
+
+	// 同一会话第二条需等待约 1 秒
+	if d := l.Reserve("123"); d != 0 {
+		t.Errorf("首条消息不应等待，实际等待 %s", d)
+	}
+	if d := l.Reserve("123"); d < 900*time.Millisecond || d > time.Second {
+		t.Errorf("同一会话的第二条应等待约 1 秒，实际等待 %s", d)
+	}
+
+	// 其它会话只受总限额约束
+	if d := l.Reserve("456"); d > 100*time.Millisecond {
+		t.Errorf("其它会话不应等待过久，实际等待 %s", d)
+	}
+}
+
+func TestRateLimiter_Group(t *testing.T) {
+	l := NewRateLimiter(0, 0, 2)
+
+	l.Reserve("-100")
+	l.Reserve("-100")
+	if d := l.Reserve("-100"); d < 59*time.Second {
+		t.Errorf("群组超出每分钟限额后应等待约 1 分钟，实际等待 %s", d)
+	}
+}
+
+func TestRateLimiter_Block(t *testing.T) {
+	l := NewRateLimiter(0, 0, 0)
+
+	l.Block("123", 5*time.Second)
+	if d := l.Reserve("123"); d < 4*time.Second {
+		t.Errorf("被限制的会话应等待约 5 秒，实际等待 %s", d)
+	}
+	if d := l.Reserve("456"); d != 0 {
+		t.Errorf("未被限制的会话不应等待，实际等待 %s", d)
+	}
+}
+
+func TestRateLimiter_Global(t *testing.T) {
+	l := NewRateLimiter(0, 2, 0)
+
+	l.Reserve("1")
+	l.Reserve("2")
+	if d := l.Reserve("3"); d < 400*time.Millisecond {
+		t.Errorf("超出每秒总限额后应等待，实际等待 %s", d)
+	}
+
+	l.Block("", 3*time.Second)
+	if d := l.Reserve("4"); d < 2*time.Second {
+		t.Errorf("暂停所有会话后应等待约 3 秒，实际等待 %s", d)
+	}
+}
+
+func TestRateLimiter_PruneChats(t *testing.T) {
+	l := NewRateLimiter(1, 0, 20)
+
+	l.Reserve("123")
+	l.Reserve("-100")
+	l.Reserve("-200")
+
+	// 模拟一分钟后：除 -200 仍在窗口内外，其它记录都已过期
+	past := time.Now().Add(-2 * time.Minute)
+	l.chatNext["123"] = past
+	l.chatNext["-100"] = past
+	l.groupSent["-100"] = []time.Time{past}
+	l.prunedAt = past
+
+	l.Reserve("456")
+	if _, ok := l.chatNext["123"]; ok {
+		t.Error("已过期的会话记录未被清理")
+	}
+	if _, ok := l.groupSent["-100"]; ok {
+		t.Error("已过期的群组记录未被清理")
+	}
+	if len(l.chatNext) != 2 || len(l.groupSent) != 1 {
+		t.Errorf("不应清理未过期的记录：%v，%v", l.chatNext, l.groupSent)
+	}
+}
@@ -0,0 +1,117 @@
+package dotg
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrNotRegistered 注册表中不存在该名称
+	ErrNotRegistered = errors.New("未注册")
+)
+
+// Route 通知的路由：通过哪个机器人发送到哪个会话
+type Route struct {
+	// 机器人在注册表中的名称
+	Bot string
+	// 目标会话
+	ChatID string
+}
+
+// Registry 按名称管理多个机器人及其通知路由，以便一个程序将通知发送到不同的机器人、会话
+//
+// 可在多个协程中使用
+//
+// reg := dotg.NewRegistry()
+// reg.AddBot("main", dotg.NewTGBot(token))
+// reg.AddRoute("alert", "main", chatID)
+// reg.SendMessage("alert", text)
+type Registry struct {
+	mu     sync.RWMutex
+	bots   map[string]*TGBot
+	routes map[string]Route
+}
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		bots:   make(map[string]*TGBot),
+		routes: make(map[string]Route),
+	}
+}
+
+// AddBot 注册机器人。已存在同名机器人时将替换
+func (r *Registry) AddBot(name string, bot *TGBot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bots[name] = bot
+}
+
+// Bot 获取已注册的机器人
+func (r *Registry) Bot(name string) (*TGBot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bot, ok := r.bots[name]
+	if !ok {
+		return nil, fmt.Errorf("机器人'%s'%w", name, ErrNotRegistered)
+	}
+
+	return bot, nil
+}
+
+// AddRoute 注册通知路由。机器人需已注册
+func (r *Registry) AddRoute(name string, botName string, chatID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.bots[botName]; !ok {
+		return fmt.Errorf("机器人'%s'%w", botName, ErrNotRegistered)
+	}
+
+	r.routes[name] = Route{Bot: botName, ChatID: chatID}
+	return nil
+}
+
+// Route 根据路由名获取机器人和目标会话
+func (r *Registry) Route(name string) (*TGBot, string, error) {
+	r.mu.RLock()
+	route, ok := r.routes[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("路由'%s'%w", name, ErrNotRegistered)
+	}
+
+	bot, err := r.Bot(route.Bot)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return bot, route.ChatID, nil
+}
+
+// SendMessage 通过路由发送 Markdown V2 文本消息
+//
+// opts 发送选项，可为 nil
+func (r *Registry) SendMessage(route string, text string, opts *SendOptions) (*Message, error) {
+	bot, chatID, err := r.Route(route)
+	if err != nil {
+		return nil, err
+	}
+
+	return bot.SendMessage(chatID, text, opts)
+}
+
+// SendMediaGroup 通过路由发送媒体集
+//
+// opts 发送选项，可为 nil
+func (r *Registry) SendMediaGroup(route string, medias []*InputMedia, opts *SendOptions) (*Message, error) {
+	bot, chatID, err := r.Route(route)
+	if err != nil {
+		return nil, err
+	}
+
+	return bot.SendMediaGroup(chatID, medias, opts)
+}
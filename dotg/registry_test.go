@@ -0,0 +1,39 @@
+package dotg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistry_SendMessage(t *testing.T) {
+	a, srvA := newFakeBot(t)
+	b, srvB := newFakeBot(t)
+
+	reg := NewRegistry()
+	reg.AddBot("a", a)
+	reg.AddBot("b", b)
+	if err := reg.AddRoute("alert", "a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.AddRoute("report", "b", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.AddRoute("none", "c", "3"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("应返回 ErrNotRegistered，实际为 %v", err)
+	}
+
+	for _, route := range []string{"alert", "report"} {
+		if _, err := reg.SendMessage(route, "test", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	callsA, callsB := srvA.CallsOf("sendMessage"), srvB.CallsOf("sendMessage")
+	if len(callsA) != 1 || callsA[0].Form.Get("chat_id") != "1" ||
+		len(callsB) != 1 || callsB[0].Form.Get("chat_id") != "2" {
+		t.Errorf("路由不符：%v %v", callsA, callsB)
+	}
+
+	if _, err := reg.SendMessage("none", "test", nil); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("应返回 ErrNotRegistered，实际为 %v", err)
+	}
+}
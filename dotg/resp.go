@@ -1,16 +1,32 @@
 package dotg
 
+import "encoding/json"
+
 // Message 为发送消息后返回的响应
 //
 // OK 为 true 表示成功，false 为失败
 type Message struct {
 	Ok          bool   `json:"ok"`
 	ErrorCode   int    `json:"error_code,omitempty"`
-	Description string `json:"description，omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// 失败时可能携带的额外信息，如速率限制时需等待的秒数
+	Parameters *ResponseParameters `json:"parameters,omitempty"`
+
+	// 成功时的结果。不同方法的结果类型不同，按需解析
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// ResponseParameters 请求失败时的额外信息
+type ResponseParameters struct {
+	// 群组已升级为超级群组时，新的 chat_id
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+
+	// 超过速率限制时，需等待的秒数
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
-// SendResult go Send() 的传回的结果
-type SendResult struct {
-	Message *Message
-	Error   error
+// ParseResult 将成功时的结果解析到 v。发送消息的方法，结果可解析为 TGMessage（媒体集为 []TGMessage）
+func (m *Message) ParseResult(v interface{}) error {
+	return json.Unmarshal(m.Result, v)
 }
@@ -4,19 +4,12 @@
 package dotg
 
 import (
-	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/donething/utils-go/dohttp"
-	"github.com/donething/utils-go/dovideo"
 	"io"
-	"mime/multipart"
 	"net/url"
-	"os"
-	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -28,6 +21,15 @@ type TGBot struct {
 
 	// API 地址。是本地服务地址，还是 TG 直连
 	addr string
+
+	// 速率限制器
+	limiter *RateLimiter
+
+	// 因速率限制而重发的最大次数
+	maxRetries int
+
+	// 执行请求的客户端。每个实例独有，设置代理不会影响其它实例
+	client dohttp.DoClient
 }
 
 const (
@@ -36,11 +38,15 @@ const (
 
 	// FileSizeThreshold TG 上传视频有2GB的限制。为容错选择 1.9 GB
 	FileSizeThreshold int64 = 2040109466
+
+	// PublicFileSizeThreshold TG 直连时，上传文件有 50 MB 的限制。为容错选择 48 MB
+	PublicFileSizeThreshold int64 = 48 * 1024 * 1024
+
+	// MaxRetries 默认的因速率限制而重发的最大次数
+	MaxRetries = 3
 )
 
 var (
-	client = dohttp.New(false, false)
-
 	ErrResend = fmt.Errorf("发送过快，要求重发")
 )
 
@@ -54,14 +60,23 @@ func NewTGBot(token string) *TGBot {
 
 		// 默认 addr
 		addr: "https://api.telegram.org",
+
+		limiter:    NewDefaultRateLimiter(),
+		maxRetries: MaxRetries,
+		client:     dohttp.New(false, false),
 	}
 }
 
-// SetProxy 设置网络代理
+// SetProxy 设置网络代理。只影响当前实例
 //
 // 格式参考 dohttp.ProxySocks5、dohttp.ProxyHttp
 func (bot *TGBot) SetProxy(proxyStr string) error {
-	return client.SetProxy(proxyStr)
+	return bot.client.SetProxy(proxyStr)
+}
+
+// SetClient 设置执行请求的客户端，可用于共享连接池、自定义超时等
+func (bot *TGBot) SetClient(client dohttp.DoClient) {
+	bot.client = client
 }
 
 // SetAddr 设置域名。开启 telegram-bot-api 本地服务时，可用本地服务地址
@@ -71,88 +86,147 @@ func (bot *TGBot) SetAddr(addr string) {
 	bot.addr = addr
 }
 
+// IsLocal 是否连接的是本地 telegram-bot-api 服务，而非 TG 直连
+func (bot *TGBot) IsLocal() bool {
+	return !strings.Contains(bot.addr, "api.telegram.org")
+}
+
+// MaxFileSize 当前连接模式下，可上传的单个文件的最大字节数
+//
+// 连接本地服务时为 FileSizeThreshold，TG 直连时为 PublicFileSizeThreshold
+func (bot *TGBot) MaxFileSize() int64 {
+	if bot.IsLocal() {
+		return FileSizeThreshold
+	}
+	return PublicFileSizeThreshold
+}
+
+// SetRateLimiter 设置速率限制器。多个使用同一 token 的实例，应共用同一个限制器
+//
+// 为 nil 时不限制发送速率
+func (bot *TGBot) SetRateLimiter(limiter *RateLimiter) {
+	bot.limiter = limiter
+}
+
+// SetMaxRetries 设置因速率限制而重发的最大次数
+func (bot *TGBot) SetMaxRetries(n int) {
+	bot.maxRetries = n
+}
+
 // Send 实际执行发送请求
 //
-// # reader 在创建处当发送完成后，自行关闭
+// chatID 目标会话，用于速率限制。与会话无关的请求可传空""
 //
-// 出错会通过 chan 回传 error
-func (bot *TGBot) Send(url string, reader io.Reader, contentType string, chResult chan SendResult) {
+// genBody 生成请求体。重发时会再次调用，以得到未被读取过的数据
+//
+// 发送前会按速率限制排队；若仍收到 429 响应，将按 parameters.retry_after 暂停该会话后重发，
+// 超过最大重发次数后，返回包装了 ErrResend 的错误
+func (bot *TGBot) Send(url string, chatID string, genBody BodyFunc) (*Message, error) {
 	tag := "Send"
-	// 发送
 	sendUrl := fmt.Sprintf(url, bot.addr, bot.token)
-	resp, err := client.Post(sendUrl, contentType, reader)
-	if err != nil {
-		chResult <- SendResult{
-			Message: nil,
-			Error:   fmt.Errorf("[%s]执行请求出错：%w", tag, err),
+
+	for i := 0; ; i++ {
+		// 按速率限制排队
+		if bot.limiter != nil {
+			bot.limiter.Wait(chatID)
+		}
+
+		msg, err := bot.post(sendUrl, genBody)
+		if err != nil {
+			return nil, fmt.Errorf("[%s]%w", tag, err)
 		}
-		return
+
+		// 速率限制
+		if msg.ErrorCode == 429 {
+			if i >= bot.maxRetries {
+				return nil, fmt.Errorf("[%s]已重发 %d 次：%w", tag, i, ErrResend)
+			}
+
+			sec := 1
+			if msg.Parameters != nil && msg.Parameters.RetryAfter > 0 {
+				sec = msg.Parameters.RetryAfter
+			}
+			if bot.limiter != nil {
+				bot.limiter.Block(chatID, time.Duration(sec)*time.Second)
+			} else {
+				time.Sleep(time.Duration(sec) * time.Second)
+			}
+
+			fmt.Printf("[%s]由于速率限制，%d 秒后重新发送\n", tag, sec)
+			continue
+		}
+
+		// 发送失败
+		if !msg.Ok {
+			return nil, fmt.Errorf("[%s]发送失败：%w", tag, &APIError{
+				Code:        msg.ErrorCode,
+				Description: msg.Description,
+				Parameters:  msg.Parameters,
+			})
+		}
+
+		// 成功
+		return msg, nil
 	}
-	defer resp.Body.Close()
+}
 
-	// 读取响应
-	bs, err := io.ReadAll(resp.Body)
+// 调用 API 方法，并将成功时的结果解析到 result。result 为 nil 时不解析
+func (bot *TGBot) call(url string, chatID string, genBody BodyFunc, result interface{}) error {
+	msg, err := bot.Send(url, chatID, genBody)
 	if err != nil {
-		chResult <- SendResult{
-			Message: nil,
-			Error:   fmt.Errorf("[%s]读取响应内容出错：%w", tag, err),
-		}
-		return
+		return err
 	}
 
-	// 解析响应
-	var msg Message
-	err = json.Unmarshal(bs, &msg)
+	if result == nil {
+		return nil
+	}
+	err = json.Unmarshal(msg.Result, result)
 	if err != nil {
-		chResult <- SendResult{
-			Message: nil,
-			Error:   fmt.Errorf("[%s]解析响应内容出错：%w", tag, err),
-		}
-		return
+		return fmt.Errorf("解析结果出错：%w", err)
 	}
 
-	// 速率限制
-	if msg.ErrorCode == 429 {
-		secStr := msg.Description[strings.LastIndex(msg.Description, " ")+1:]
-		sec, err := strconv.Atoi(secStr)
-		if err != nil {
-			chResult <- SendResult{
-				Message: nil,
-				Error:   fmt.Errorf("[%s]解析速率限制的等待时长时出错：%w", tag, err),
-			}
-			return
-		}
+	return nil
+}
 
-		fmt.Printf("[%s]由于速率限制，等待 %d 秒后重新发送\n", tag, sec)
-		time.Sleep(time.Duration(sec+1) * time.Second)
-		// 重发
-		chResult <- SendResult{
-			Message: nil,
-			Error:   ErrResend,
-		}
-		return
+// 执行一次请求，并解析响应
+func (bot *TGBot) post(sendUrl string, genBody BodyFunc) (*Message, error) {
+	body, contentType, err := genBody()
+	if err != nil {
+		return nil, fmt.Errorf("生成请求体出错：%w", err)
+	}
+	// 发送结束后释放请求体，确保调用方关闭数据源前，已不再读取
+	if c, ok := body.(io.Closer); ok {
+		defer c.Close()
 	}
 
-	// 发送失败
-	if !msg.Ok {
-		chResult <- SendResult{
-			Message: nil,
-			Error:   fmt.Errorf("[%s]发送失败：%d %s", tag, msg.ErrorCode, msg.Description),
-		}
-		return
+	resp, err := bot.client.Post(sendUrl, contentType, body)
+	if err != nil {
+		return nil, fmt.Errorf("执行请求出错(%w)：%w", ErrNetwork, err)
+	}
+	defer resp.Body.Close()
+
+	// 读取响应
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应内容出错(%w)：%w", ErrNetwork, err)
 	}
 
-	// 成功
-	chResult <- SendResult{
-		Message: &msg,
-		Error:   nil,
+	// 解析响应
+	var msg Message
+	err = json.Unmarshal(bs, &msg)
+	if err != nil {
+		return nil, fmt.Errorf("解析响应内容出错：%w", err)
 	}
+
+	return &msg, nil
 }
 
 // SendMessage 发送 Markdown V2 文本消息
 //
 // 注意使用 EscapeMk、LegalMk 来转义字符
-func (bot *TGBot) SendMessage(chatID string, text string) (*Message, error) {
+//
+// opts 发送选项，可为 nil
+func (bot *TGBot) SendMessage(chatID string, text string, opts *SendOptions) (*Message, error) {
 	tag := "SendMessage"
 	form := url.Values{
 		"chat_id":    []string{chatID},
@@ -160,25 +234,17 @@ func (bot *TGBot) SendMessage(chatID string, text string) (*Message, error) {
 		"parse_mode": []string{"MarkdownV2"},
 	}
 
-	var chResult = make(chan SendResult)
-
-	// 调用发送。因为使用了 chan 传输结果，所以需要开启新的协程发送
-	go bot.Send(urlSendMsg, bytes.NewReader([]byte(form.Encode())),
-		"application/x-www-form-urlencoded", chResult)
-
-	// 等待发送完成，分情况处理
-	result := <-chResult
-	// 有发送错误
-	if result.Error != nil && !errors.Is(result.Error, ErrResend) {
-		return nil, fmt.Errorf("[%s]%s", tag, result.Error)
+	err := opts.apply(urlSendMsg, form)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
 	}
-	// 需要重发
-	if errors.Is(result.Error, ErrResend) {
-		return bot.SendMessage(chatID, text)
+
+	msg, err := bot.Send(urlSendMsg, chatID, formBody(form))
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
 	}
 
-	// 发送成功
-	return result.Message, nil
+	return msg, nil
 }
 
 // SendMediaGroup 发送一个媒体集
@@ -187,204 +253,81 @@ func (bot *TGBot) SendMessage(chatID string, text string) (*Message, error) {
 //
 // 注意使用 EscapeMk、LegalMk 来转义字符
 //
-// # 当 InputMedia.Media 为 reader 时，在创建处发送完成后，自行关闭
+// 媒体数据为 io.Reader 时，发送结束后，若其实现了 io.Closer 将被关闭；
+// 为保证能在速率限制后重发，应传递可回退的 io.ReadSeeker（如 *os.File），或者可重新打开的 Opener
 //
 // 因为原生 api 限制发送文件的大小，若需发送大文件，可以运行本地 TG 服务,
 // 设置 tg.SetAddr("http://127.0.0.1:1234")后，来发送
 // @see https://stackoverflow.com/a/75012096
 // @see https://hdcola.medium.com/telegram-bot-api-server%E4%BD%9C%E5%BC%8A%E6%9D%A1-301d40bd65ba
-func (bot *TGBot) SendMediaGroup(chatID string, medias []*InputMedia) (*Message, error) {
+//
+// opts 发送选项，可为 nil。媒体集不支持链接预览
+func (bot *TGBot) SendMediaGroup(chatID string, medias []*InputMedia, opts *SendOptions) (*Message, error) {
 	tag := "SendMediaGroup"
 
-	// 使用 bytes.Buffer{} 还是会将数据全部写入内存，所以使用 pipe 替代
-	pr, pw := io.Pipe()
-	// 组装 multipart 文件上传请求的参数
-	writer := multipart.NewWriter(pw)
-
-	// 接收执行请求的错误
-	var chResult = make(chan SendResult)
-
-	// 新协程执行请求
-	go bot.Send(urlSendMediaGroup, pr, writer.FormDataContentType(), chResult)
-
-	// 添加 chat_id 字段
-	err := writer.WriteField("chat_id", chatID)
-	if err != nil {
-		return nil, fmt.Errorf("[%s]写入 chat_id 出错：%w", tag, err)
-	}
-
-	// 写入媒体
-	for i, m := range medias {
-		// 如果媒体数据的来源是 Reader，则读取
-		if reader, ok := m.Media.(io.Reader); ok {
-			// 写入媒体
-			partMedia, err := writer.CreateFormFile(fmt.Sprintf("media%d", i), m.Name)
-			if err != nil {
-				return nil, fmt.Errorf("[%s]创建视频表单信息出错：%w", tag, err)
+	// 发送结束后，关闭作为数据源的 Reader
+	defer func() {
+		for _, m := range medias {
+			if c, ok := m.Media.(io.Closer); ok {
+				c.Close()
 			}
-
-			_, err = io.Copy(partMedia, reader)
-			if err != nil {
-				return nil, fmt.Errorf("[%s]复制视频文件流出错：%w", tag, err)
+			if c, ok := m.Thumbnail.(io.Closer); ok {
+				c.Close()
 			}
+		}
+	}()
 
-			if r, ok := m.Media.(io.ReadCloser); ok {
-				r.Close()
-			}
+	// 需上传的文件在表单中单独发送，媒体信息中用"attach://"指向它们。不修改传入的媒体信息，以便重发
+	files := make([]formFile, 0)
+	items := make([]InputMedia, len(medias))
+	for i, m := range medias {
+		item := *m
 
-			m.Media = fmt.Sprintf("attach://media%d", i)
+		// 写入媒体
+		if isUpload(m.Media) {
+			field := fmt.Sprintf("media%d", i)
+			files = append(files, formFile{field: field, name: m.Name, src: m.Media})
+			item.Media = "attach://" + field
 		}
 
 		// 写入缩略图
-		if reader, ok := m.Thumbnail.(io.Reader); ok {
-			partThumbnail, err := writer.CreateFormFile(fmt.Sprintf("thumb%d", i), m.Name)
-			if err != nil {
-				return nil, fmt.Errorf("[%s]创建封面表单信息出错：%w", tag, err)
-			}
-
-			_, err = io.Copy(partThumbnail, reader)
-			if err != nil {
-				return nil, fmt.Errorf("[%s]复制封面文件流出错：%w", tag, err)
-			}
-
-			if r, ok := m.Thumbnail.(io.ReadCloser); ok {
-				r.Close()
-			}
-
-			m.Thumbnail = fmt.Sprintf("attach://thumb%d", i)
+		if isUpload(m.Thumbnail) {
+			field := fmt.Sprintf("thumb%d", i)
+			files = append(files, formFile{field: field, name: m.Name, src: m.Thumbnail})
+			item.Thumbnail = "attach://" + field
 		}
 
 		// 设置默认的标题解析模式 MarkdownV2
-		if m.ParseMode == "" {
-			m.ParseMode = "MarkdownV2"
+		if item.ParseMode == "" {
+			item.ParseMode = ParseMK2
 		}
+
+		items[i] = item
 	}
 
 	// 发送媒体组的额外信息（标题、对应媒体等）
-	mediaFormBs, err := json.Marshal(medias)
+	mediaFormBs, err := json.Marshal(items)
 	if err != nil {
 		return nil, fmt.Errorf("[%s]序列化媒体组的信息出错：%w", tag, err)
 	}
 
-	err = writer.WriteField("media", string(mediaFormBs))
-	if err != nil {
-		return nil, fmt.Errorf("[%s]写入 media 出错：%w", tag, err)
+	form := url.Values{
+		"chat_id": []string{chatID},
+		"media":   []string{string(mediaFormBs)},
 	}
 
-	err = writer.Close()
+	err = opts.apply(urlSendMediaGroup, form)
 	if err != nil {
-		return nil, fmt.Errorf("[%s]关闭 multipart writer 出错：%w", tag, err)
+		return nil, fmt.Errorf("[%s]%w", tag, err)
 	}
 
-	// 注意要关闭 pipe writer 否则会卡主
-	err = pw.Close()
+	msg, err := bot.Send(urlSendMediaGroup, chatID, multipartBody(form, files))
 	if err != nil {
-		return nil, fmt.Errorf("[%s]关闭 pipe writer 出错：%w", tag, err)
-	}
-
-	// 发送完数据，等待执行完成，分情况处理
-	result := <-chResult
-	// 有发送错误
-	if result.Error != nil && !errors.Is(result.Error, ErrResend) {
-		return nil, fmt.Errorf("[%s]%s", tag, result.Error)
-	}
-	// 需要重发
-	if errors.Is(result.Error, ErrResend) {
-		return bot.SendMediaGroup(chatID, medias)
+		return nil, fmt.Errorf("[%s]%w", tag, err)
 	}
 
 	// 发送成功
-	return result.Message, nil
-}
-
-// SendVideo 发送视频。只支持在 TG Local Server 模式下发送视频
-//
-// 仅适合 TG Local Server 模式，因为媒体数据是通过"file://"协议传的，而不是建立pipe管道读写数据，
-// 这样避免 write: connection reset by peer，也更稳定
-//
-// fileSizeThreshold 设置视频分段的的字节数，为 0 不分段
-//
-// tmpDir 设置临时文件的目录（为空""则在文件同目录）
-//
-// delete 是否保留原文件（或转码后的视频文件）
-func (bot *TGBot) SendVideo(chatID string, title string, path string,
-	fileSizeThreshold int64, tmpDir string, delete bool) (*Message, error) {
-	tag := "SendVideo"
-	// 发送完后，删除临时文件
-	var delFiles = make([]string, 0)
-	defer func() {
-		for _, p := range delFiles {
-			err := os.RemoveAll(p)
-			if err != nil {
-				fmt.Printf("[%s]删除文件'%s'出错：%s\n", tag, p, err)
-			}
-		}
-	}()
-
-	newPath := path
-	// 不是 mp4 格式的视频，才要转码为 mp4
-	if strings.ToLower(filepath.Ext(path)) != ".mp4" {
-		newPath = strings.TrimSuffix(path, filepath.Ext(path)) + ".mp4"
-		err := dovideo.Convt(path, newPath)
-		if err != nil {
-			return nil, fmt.Errorf("[%s]转换视频编码出错：%w", tag, err)
-		}
-
-		// 删除原视频。本来可以放在末尾的，但是占用磁盘空间，所以在转码成功后删除
-		err = os.Remove(path)
-		if err != nil {
-			return nil, fmt.Errorf("[%s]删除原视频出错：%w", tag, err)
-		}
-	}
-
-	// 获取文件大小
-	info, err := os.Stat(newPath)
-	if err != nil {
-		return nil, fmt.Errorf("[%s]获取文件信息出错：%w", tag, err)
-	}
-	// 如果目标视频超过了设置的最大值，就切割
-	dstPaths := []string{newPath}
-	if fileSizeThreshold != 0 && info.Size() > fileSizeThreshold {
-		dstPaths, err = dovideo.CutMp4(path, fileSizeThreshold, tmpDir)
-		if err != nil {
-			return nil, fmt.Errorf("[%s]切割视频出错：%w", tag, err)
-		}
-	}
-
-	// 需要发送媒体
-	var medias = make([]*InputMedia, len(dstPaths))
-	for i, p := range dstPaths {
-		// 创建媒体信息
-		// 仅第一个媒体携带标题信息，作为整个媒体集的标题
-		media, err := GenVideoMedia(p, "")
-		if err != nil {
-			return nil, fmt.Errorf("[%s]生成媒体信息出错：%w", tag, err)
-		}
-		if i == 0 {
-			media.Caption = title
-		}
-
-		// 多分段时，加上 Pn 标识
-		if len(dstPaths) >= 2 {
-			media.Name = fmt.Sprintf("P%02d", i+1)
-		}
-		medias[i] = media
-	}
-
-	if len(dstPaths) >= 2 {
-		// 谨慎：多片段时，删除临时视频目录
-		delFiles = append(delFiles, filepath.Dir(dstPaths[0]))
-	} else {
-		// 只需删除封面
-		delFiles = append(delFiles, strings.TrimSuffix(newPath, filepath.Ext(newPath))+".jpg")
-	}
-	// 不保留原文件，删除
-	if delete {
-		delFiles = append(delFiles, newPath)
-	}
-
-	// 发送
-	return bot.SendMediaGroup(chatID, medias)
+	return msg, nil
 }
 
 // EscapeMk 转义将被 Markdown V2 格式字符包围的文本
@@ -2,10 +2,15 @@ package dotg
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/donething/utils-go/dofile"
 	"github.com/donething/utils-go/dohttp"
+	"github.com/donething/utils-go/dotg/dotgtest"
 	"github.com/donething/utils-go/dovideo"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -31,7 +36,7 @@ func init() {
 func TestTGBot_SendMessage(t *testing.T) {
 	txt := EscapeMk("测#试Markdown文本*消息*：") + "[搜索](https://www.google.com/)"
 	for {
-		msg, err := tg.SendMessage(chatID, txt)
+		msg, err := tg.SendMessage(chatID, txt, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -61,7 +66,7 @@ func TestSendMediaGroupPic(t *testing.T) {
 		},
 	}
 
-	msg, err := tg.SendMediaGroup(chatID, medias)
+	msg, err := tg.SendMediaGroup(chatID, medias, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -97,7 +102,7 @@ func TestTGBot_SendMediaGroupVideo(t *testing.T) {
 		SupportsStreaming: true,
 	}
 
-	msg, err := tg.SendMediaGroup(chatID, []*InputMedia{m})
+	msg, err := tg.SendMediaGroup(chatID, []*InputMedia{m}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -131,7 +136,7 @@ func TestReplaceMk(t *testing.T) {
 func TestTGBot_SendVideo(t *testing.T) {
 	msg, err := tg.SendVideo(os.Getenv("MY_TG_CHAT_LIVE"), "测试标题",
 		"D:/Temp/VpsGo/TYOD-263.1080p.mp4", 150*1024*1024,
-		"", false)
+		"", false, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -139,3 +144,116 @@ func TestTGBot_SendVideo(t *testing.T) {
 	t.Logf("%+v\n", msg)
 	time.Sleep(100 * time.Second)
 }
+
+// 创建指向模拟服务的机器人
+func newFakeBot(t *testing.T) (*TGBot, *dotgtest.Server) {
+	srv := dotgtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	bot := NewTGBot(dotgtest.Token)
+	bot.SetAddr(srv.URL)
+	return bot, srv
+}
+
+func TestTGBot_SendRetry(t *testing.T) {
+	bot, srv := newFakeBot(t)
+
+	// 第一次请求返回 429，之后成功
+	srv.RateLimit("sendMediaGroup", 1, 1)
+
+	// 可回退的数据源能够重发
+	medias := []*InputMedia{{Type: TypePhoto, Media: bytes.NewReader([]byte("photo")), Name: "a.jpg"}}
+	if _, err := bot.SendMediaGroup("123", medias, nil); err != nil {
+		t.Fatal(err)
+	}
+	calls := srv.CallsOf("sendMediaGroup")
+	if len(calls) != 2 {
+		t.Fatalf("应请求 2 次，实际请求 %d 次", len(calls))
+	}
+	if string(calls[1].Files["media0"].Content) != "photo" {
+		t.Errorf("重发的数据不符：%s", calls[1].Files["media0"].Content)
+	}
+
+	// 不可回退的数据源不能重发
+	srv.RateLimit("sendMediaGroup", 1, 1)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("photo"))
+		pw.Close()
+	}()
+	medias = []*InputMedia{{Type: TypePhoto, Media: pr, Name: "a.jpg"}}
+	if _, err := bot.SendMediaGroup("123", medias, nil); !errors.Is(err, ErrNotRewindable) {
+		t.Errorf("应返回 ErrNotRewindable，实际为 %v", err)
+	}
+
+	// 超过最大重发次数
+	bot.SetMaxRetries(0)
+	srv.RateLimit("sendMessage", 1, 1)
+	if _, err := bot.SendMessage("123", "test", nil); !errors.Is(err, ErrResend) {
+		t.Errorf("应返回 ErrResend，实际为 %v", err)
+	}
+}
+
+func TestTGBot_SetProxy(t *testing.T) {
+	a, b := NewTGBot("a"), NewTGBot("b")
+	if err := a.SetProxy(dohttp.ProxySocks5); err != nil {
+		t.Fatal(err)
+	}
+
+	// 设置代理只影响当前实例
+	req := httptest.NewRequest(http.MethodGet, "https://api.telegram.org", nil)
+	u, _ := b.client.Transport.(*http.Transport).Proxy(req)
+	if u != nil && u.String() == dohttp.ProxySocks5 {
+		t.Errorf("设置代理影响了其它实例")
+	}
+}
+
+// 记录是否已被关闭的数据源
+type closeRecorder struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (r *closeRecorder) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func TestTGBot_SendRelease(t *testing.T) {
+	// 不读取请求体，直接返回错误
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	bot := NewTGBot(dotgtest.Token)
+	bot.SetAddr(srv.URL)
+
+	src := &closeRecorder{
+		Reader: io.LimitReader(neverEnding('a'), 16<<20),
+		closed: make(chan struct{}),
+	}
+	open := Opener(func() (io.ReadCloser, error) { return src, nil })
+	medias := []*InputMedia{{Type: TypePhoto, Media: open, Name: "a.jpg"}}
+	if _, err := bot.SendMediaGroup("123", medias, nil); err == nil {
+		t.Fatal("应返回错误")
+	}
+
+	// 返回时，写入协程应已结束，并关闭了数据源
+	select {
+	case <-src.closed:
+	default:
+		t.Error("发送出错后，数据源未被关闭")
+	}
+}
+
+// 无限重复同一字节的 io.Reader
+type neverEnding byte
+
+func (b neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
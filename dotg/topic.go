@@ -0,0 +1,100 @@
+package dotg
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// 话题（论坛）管理。机器人需为群组的管理员，且拥有 can_manage_topics 权限
+//
+// 发送消息到话题中，可设置 SendOptions.MessageThreadID
+
+const (
+	urlCreateForumTopic = "%s/%s/createForumTopic"
+	urlEditForumTopic   = "%s/%s/editForumTopic"
+	urlCloseForumTopic  = "%s/%s/closeForumTopic"
+	urlReopenForumTopic = "%s/%s/reopenForumTopic"
+	urlDeleteForumTopic = "%s/%s/deleteForumTopic"
+)
+
+// 话题图标可选的颜色
+const (
+	TopicColorBlue   = 0x6FB9F0
+	TopicColorYellow = 0xFFD67E
+	TopicColorPurple = 0xCB86DB
+	TopicColorGreen  = 0x8EEE98
+	TopicColorPink   = 0xFF93B2
+	TopicColorRed    = 0xFB6F5F
+)
+
+// CreateForumTopic 创建话题
+//
+// iconColor 图标的颜色，可选 TopicColorBlue 等，为 0 时由 TG 选择
+//
+// iconCustomEmojiID 自定义表情图标的 ID，可为空""
+func (bot *TGBot) CreateForumTopic(chatID string, name string, iconColor int,
+	iconCustomEmojiID string) (*ForumTopic, error) {
+	tag := "CreateForumTopic"
+	form := url.Values{
+		"chat_id": []string{chatID},
+		"name":    []string{name},
+	}
+	if iconColor != 0 {
+		form.Set("icon_color", strconv.Itoa(iconColor))
+	}
+	if iconCustomEmojiID != "" {
+		form.Set("icon_custom_emoji_id", iconCustomEmojiID)
+	}
+
+	var topic ForumTopic
+	err := bot.call(urlCreateForumTopic, chatID, formBody(form), &topic)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return &topic, nil
+}
+
+// EditForumTopic 修改话题的名称、图标
+//
+// name、iconCustomEmojiID 为空""时不修改
+func (bot *TGBot) EditForumTopic(chatID string, threadID int64, name string, iconCustomEmojiID string) error {
+	form := url.Values{}
+	if name != "" {
+		form.Set("name", name)
+	}
+	if iconCustomEmojiID != "" {
+		form.Set("icon_custom_emoji_id", iconCustomEmojiID)
+	}
+
+	return bot.topicCall(urlEditForumTopic, "EditForumTopic", chatID, threadID, form)
+}
+
+// CloseForumTopic 关闭话题
+func (bot *TGBot) CloseForumTopic(chatID string, threadID int64) error {
+	return bot.topicCall(urlCloseForumTopic, "CloseForumTopic", chatID, threadID, url.Values{})
+}
+
+// ReopenForumTopic 重新开启已关闭的话题
+func (bot *TGBot) ReopenForumTopic(chatID string, threadID int64) error {
+	return bot.topicCall(urlReopenForumTopic, "ReopenForumTopic", chatID, threadID, url.Values{})
+}
+
+// DeleteForumTopic 删除话题及其中的所有消息
+func (bot *TGBot) DeleteForumTopic(chatID string, threadID int64) error {
+	return bot.topicCall(urlDeleteForumTopic, "DeleteForumTopic", chatID, threadID, url.Values{})
+}
+
+// 调用操作话题的方法
+func (bot *TGBot) topicCall(u string, tag string, chatID string, threadID int64, form url.Values) error {
+	form.Set("chat_id", chatID)
+	form.Set("message_thread_id", strconv.FormatInt(threadID, 10))
+
+	err := bot.call(u, chatID, formBody(form), nil)
+	if err != nil {
+		return fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package dotg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	urlGetUpdates = "%s/%s/getUpdates"
+)
+
+// GetUpdates 通过长轮询获取更新，如收到的消息、投票的作答(PollAnswer)
+//
+// 不能与 Webhook 同时使用
+//
+// offset 应为上次获取到的最大 UpdateID + 1，以确认之前的更新，否则会重复获取
+//
+// limit 最多获取的条数（1-100），为 0 时为 100
+//
+// timeout 长轮询的超时（秒），为 0 时为短轮询
+//
+// allowedUpdates 需获取的更新类型，如 UpdatePollAnswer。为 nil 时沿用上次的设置
+func (bot *TGBot) GetUpdates(offset int64, limit int, timeout int, allowedUpdates []string) ([]*Update, error) {
+	tag := "GetUpdates"
+	form := url.Values{
+		"offset":  []string{strconv.FormatInt(offset, 10)},
+		"timeout": []string{strconv.Itoa(timeout)},
+	}
+	if limit != 0 {
+		form.Set("limit", strconv.Itoa(limit))
+	}
+	if allowedUpdates != nil {
+		bs, err := json.Marshal(allowedUpdates)
+		if err != nil {
+			return nil, fmt.Errorf("[%s]序列化更新类型出错：%w", tag, err)
+		}
+		form.Set("allowed_updates", string(bs))
+	}
+
+	var updates []*Update
+	err := bot.call(urlGetUpdates, "", formBody(form), &updates)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	return updates, nil
+}
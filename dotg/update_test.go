@@ -0,0 +1,27 @@
+package dotg
+
+import (
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"testing"
+)
+
+func TestTGBot_GetUpdates(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	srv.Handle("getUpdates", func(call *dotgtest.Call) (interface{}, *dotgtest.APIError) {
+		return []*Update{{UpdateID: 5, PollAnswer: &PollAnswer{PollID: "p1", User: &User{ID: 2},
+			OptionIDs: []int{1}}}}, nil
+	})
+
+	updates, err := bot.GetUpdates(5, 0, 0, []string{UpdatePollAnswer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := srv.CallsOf("getUpdates")[0].Form
+	if form.Get("allowed_updates") != `["poll_answer"]` || form.Get("offset") != "5" {
+		t.Errorf("表单不符：%v", form)
+	}
+	if len(updates) != 1 || updates[0].PollAnswer == nil || updates[0].PollAnswer.OptionIDs[0] != 1 {
+		t.Errorf("更新不符：%+v", updates)
+	}
+}
@@ -3,46 +3,396 @@ package dotg
 import (
 	"fmt"
 	"github.com/donething/utils-go/dovideo"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// VideoJob 发送视频的任务
+//
+// 按阶段执行：探测、转码（仅非 mp4 时）、切割、生成封面、上传、清理。
+// 转码、切割、封面产生的文件都在临时工作目录中，无论成功与否，结束时都会删除该目录
+type VideoJob struct {
+	ChatID string
+	// 标题，作为媒体集的标题
+	Title string
+	// 视频的路径
+	Path string
+
+	// 视频分段的字节数。为 0 时按连接模式自动选择，参考 TGBot.MaxFileSize()
+	SegmentSize int64
+
+	// 在该目录下创建临时工作目录。为空""则在视频同目录下创建
+	TmpDir string
+
+	// 发送成功后是否保留原视频。发送失败时始终保留
+	KeepSource bool
+
+	// 只探测视频并报告将发送的内容，不转码、不切割、不发送
+	DryRun bool
+
+	// 发送选项，可为 nil
+	Options *SendOptions
+}
+
+// VideoSegment 将发送的视频分段
+type VideoSegment struct {
+	// 分段的路径。DryRun 时尚未切割，为空""
+	Path string
+	// 分段的字节数。DryRun 时为估算值
+	Size int64
+	// 分段的文件名，多分段时为"P01"、"P02"等
+	Name string
+}
+
+// VideoReport 发送视频的报告
+type VideoReport struct {
+	// 原视频的信息
+	Size     int64
+	Width    int
+	Height   int
+	Duration int
+
+	// 是否需要转码为 mp4
+	Transcode bool
+	// 实际（或 DryRun 时将要）发送的分段
+	Segments []*VideoSegment
+
+	// 是否已删除原视频
+	SourceDeleted bool
+
+	// 发送的结果（第一批媒体的），DryRun 时为 nil
+	Message *Message
+}
+
+// 执行 VideoJob 时的状态
+type videoPipeline struct {
+	bot    *TGBot
+	job    *VideoJob
+	report *VideoReport
+
+	// 临时工作目录
+	workspace string
+	// 待发送的视频（原视频或转码后的视频）
+	video string
+	// 实际使用的分段大小。job.SegmentSize 为 0 或超过 TG 的限制时为 TGBot.MaxFileSize()
+	segmentSize int64
+}
+
+// SendVideo 发送视频
+//
+// 连接本地服务（TG Local Server）时，媒体数据是通过"file://"协议传的，而不是建立pipe管道读写数据，
+// 这样避免 write: connection reset by peer，也更稳定；TG 直连时，通过 multipart 流式上传
+//
+// fileSizeThreshold 设置视频分段的的字节数，为 0 时按连接模式自动选择，参考 MaxFileSize()。
+// TG 直连时，超过 PublicFileSizeThreshold 的值也将按该值分段
+//
+// tmpDir 设置临时文件的目录（为空""则在文件同目录）
+//
+// delete 发送成功后是否删除原文件
+//
+// opts 发送选项，可为 nil
+//
+// 更多选项可使用 SendVideoJob
+func (bot *TGBot) SendVideo(chatID string, title string, path string,
+	fileSizeThreshold int64, tmpDir string, delete bool, opts *SendOptions) (*Message, error) {
+	report, err := bot.SendVideoJob(&VideoJob{
+		ChatID:      chatID,
+		Title:       title,
+		Path:        path,
+		SegmentSize: fileSizeThreshold,
+		TmpDir:      tmpDir,
+		KeepSource:  !delete,
+		Options:     opts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Message, nil
+}
+
+// SendVideoJob 执行发送视频的任务
+func (bot *TGBot) SendVideoJob(job *VideoJob) (*VideoReport, error) {
+	tag := "SendVideoJob"
+	p := &videoPipeline{bot: bot, job: job, report: &VideoReport{}, video: job.Path, segmentSize: job.SegmentSize}
+
+	// 分段大小不能超过 TG 的限制。不修改 job，以便在其它 TGBot 中复用
+	if p.segmentSize <= 0 || p.segmentSize > bot.MaxFileSize() {
+		p.segmentSize = bot.MaxFileSize()
+	}
+
+	err := p.probe()
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+
+	if job.DryRun {
+		p.plan()
+		return p.report, nil
+	}
+
+	// 创建临时工作目录，结束时删除
+	dir := job.TmpDir
+	if dir == "" {
+		dir = filepath.Dir(job.Path)
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]创建临时目录出错：%w", tag, err)
+	}
+	p.workspace, err = os.MkdirTemp(dir, "tgvideo_")
+	if err != nil {
+		return nil, fmt.Errorf("[%s]创建临时工作目录出错：%w", tag, err)
+	}
+	defer p.cleanup()
+
+	stages := []func() error{p.transcode, p.split, p.upload}
+	for _, stage := range stages {
+		if err = stage(); err != nil {
+			return p.report, fmt.Errorf("[%s]%w", tag, err)
+		}
+	}
+
+	// 发送成功，按需删除原视频
+	if !job.KeepSource {
+		err = os.Remove(job.Path)
+		if err != nil {
+			return p.report, fmt.Errorf("[%s]删除原视频出错：%w", tag, err)
+		}
+		p.report.SourceDeleted = true
+	}
+
+	return p.report, nil
+}
+
+// 探测视频的信息
+func (p *videoPipeline) probe() error {
+	info, err := os.Stat(p.job.Path)
+	if err != nil {
+		return fmt.Errorf("获取文件信息出错：%w", err)
+	}
+	p.report.Size = info.Size()
+
+	p.report.Width, p.report.Height, err = dovideo.GetResolution(p.job.Path)
+	if err != nil {
+		return fmt.Errorf("获取视频分辨率出错：%w", err)
+	}
+	p.report.Duration, err = dovideo.GetDuration(p.job.Path)
+	if err != nil {
+		return fmt.Errorf("获取视频时长出错：%w", err)
+	}
+
+	// 不是 mp4 格式的视频，才要转码为 mp4
+	p.report.Transcode = strings.ToLower(filepath.Ext(p.job.Path)) != ".mp4"
+	return nil
+}
+
+// 估算将发送的分段，用于 DryRun。转码只是更换封装格式（-c copy），所以按原视频的大小估算
+func (p *videoPipeline) plan() {
+	n := int(math.Ceil(float64(p.report.Size) / float64(p.segmentSize)))
+	if n <= 1 {
+		p.report.Segments = []*VideoSegment{{Path: p.job.Path, Size: p.report.Size}}
+		if p.report.Transcode {
+			p.report.Segments[0].Path = ""
+		}
+		return
+	}
+
+	p.report.Segments = make([]*VideoSegment, n)
+	for i := range p.report.Segments {
+		size := p.segmentSize
+		if i == n-1 {
+			size = p.report.Size - int64(n-1)*p.segmentSize
+		}
+		p.report.Segments[i] = &VideoSegment{Size: size, Name: fmt.Sprintf("P%02d", i+1)}
+	}
+}
+
+// 转码为 mp4，保存到临时工作目录
+func (p *videoPipeline) transcode() error {
+	if !p.report.Transcode {
+		return nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(p.job.Path), filepath.Ext(p.job.Path)) + ".mp4"
+	newPath := filepath.Join(p.workspace, name)
+	err := dovideo.Convt(p.job.Path, newPath)
+	if err != nil {
+		return fmt.Errorf("转换视频编码出错：%w", err)
+	}
+
+	p.video = newPath
+	return nil
+}
+
+// 超过分段大小时，切割到临时工作目录
+func (p *videoPipeline) split() error {
+	info, err := os.Stat(p.video)
+	if err != nil {
+		return fmt.Errorf("获取文件信息出错：%w", err)
+	}
+
+	if info.Size() <= p.segmentSize {
+		p.report.Segments = []*VideoSegment{{Path: p.video, Size: info.Size()}}
+		return nil
+	}
+
+	paths, err := dovideo.CutMp4(p.video, p.segmentSize, filepath.Join(p.workspace, "segments"))
+	if err != nil {
+		return fmt.Errorf("切割视频出错：%w", err)
+	}
+
+	p.report.Segments = make([]*VideoSegment, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("获取分段信息出错：%w", err)
+		}
+		p.report.Segments[i] = &VideoSegment{Path: path, Size: info.Size(), Name: fmt.Sprintf("P%02d", i+1)}
+	}
+
+	return nil
+}
+
+// 生成封面到临时工作目录，并发送
+func (p *videoPipeline) upload() error {
+	thumbDir := filepath.Join(p.workspace, "thumbs")
+	err := os.MkdirAll(thumbDir, 0755)
+	if err != nil {
+		return fmt.Errorf("创建封面目录出错：%w", err)
+	}
+
+	medias := make([]*InputMedia, len(p.report.Segments))
+	paths := make([]string, len(p.report.Segments))
+	for i, seg := range p.report.Segments {
+		thumbPath := filepath.Join(thumbDir, fmt.Sprintf("%02d.jpg", i+1))
+		media, err := genVideoMedia(seg.Path, "", p.bot.IsLocal(), thumbPath)
+		if err != nil {
+			return fmt.Errorf("生成媒体信息出错：%w", err)
+		}
+
+		// 仅第一个媒体携带标题信息，作为整个媒体集的标题
+		if i == 0 {
+			media.Caption = p.job.Title
+		}
+		// 多分段时，加上 Pn 标识
+		if seg.Name != "" {
+			media.Name = seg.Name
+		}
+
+		medias[i] = media
+		paths[i] = seg.Path
+	}
+
+	p.report.Message, err = p.bot.sendMediaBatches(p.job.ChatID, medias, paths, p.job.Options)
+	return err
+}
+
+// 删除临时工作目录
+func (p *videoPipeline) cleanup() {
+	err := os.RemoveAll(p.workspace)
+	if err != nil {
+		fmt.Printf("[videoPipeline]删除临时工作目录'%s'出错：%s\n", p.workspace, err)
+	}
+}
+
+// 分批发送媒体。每批最多 10 个媒体；TG 直连时，每批的文件大小总和不超过 PublicFileSizeThreshold
+//
+// paths 为媒体对应的本地文件路径，用于计算大小
+//
+// 返回第一批（携带标题）的发送结果
+func (bot *TGBot) sendMediaBatches(chatID string, medias []*InputMedia, paths []string,
+	opts *SendOptions) (*Message, error) {
+	tag := "sendMediaBatches"
+	var first *Message
+
+	start := 0
+	var total int64
+	for i := range medias {
+		info, err := os.Stat(paths[i])
+		if err != nil {
+			return nil, fmt.Errorf("[%s]获取文件信息出错：%w", tag, err)
+		}
+
+		// 加入当前媒体后超出限制，先发送之前的媒体
+		full := i-start >= 10 || (!bot.IsLocal() && i > start && total+info.Size() > PublicFileSizeThreshold)
+		if full {
+			msg, err := bot.SendMediaGroup(chatID, medias[start:i], opts)
+			if err != nil {
+				return nil, fmt.Errorf("[%s]%w", tag, err)
+			}
+			if first == nil {
+				first = msg
+			}
+			start, total = i, 0
+		}
+		total += info.Size()
+	}
+
+	msg, err := bot.SendMediaGroup(chatID, medias[start:], opts)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]%w", tag, err)
+	}
+	if first == nil {
+		first = msg
+	}
+
+	return first, nil
+}
+
 // GenVideoMedia 生成上传视频到TG的 InputMedia 实例
 //
 // 仅适合 TG Local Server 模式，因为媒体数据是通过"file://"协议传的，而不是建立pipe管道读写数据，
 // 这样避免 write: connection reset by peer，也更稳定
 //
-// 会转码、生成封面，不会切割长视频
-//
-// 注意：转码成功时会删除原视频
+// 会生成封面，不会转码、切割长视频
 //
-// 会返回新视频、封面的路径，以便上传后删除
+// 封面保存在视频同目录下（同名 .jpg），以便上传后删除
 func GenVideoMedia(path string, title string) (*InputMedia, error) {
-	tag := "GenVideoMedia"
+	return genVideoMedia(path, title, true, thumbPathOf(path))
+}
+
+// GenVideoMediaUpload 生成上传视频到TG的 InputMedia 实例
+//
+// 适合 TG 直连模式，视频、封面在发送时才打开，通过 multipart 流式上传。注意 TG 直连时文件不能超过 50 MB
+//
+// 封面保存在视频同目录下（同名 .jpg），以便上传后删除
+func GenVideoMediaUpload(path string, title string) (*InputMedia, error) {
+	return genVideoMedia(path, title, false, thumbPathOf(path))
+}
+
+// 生成上传视频到TG的 InputMedia 实例
+//
+// local 为 true 时，媒体数据通过"file://"协议传递；否则发送时打开文件上传
+//
+// thumbPath 封面的保存路径
+func genVideoMedia(path string, title string, local bool, thumbPath string) (*InputMedia, error) {
+	tag := "genVideoMedia"
 
 	// 获取视频封面
-	thumbPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".jpg"
 	err := dovideo.GetFrame(path, thumbPath, "00:00:03", "")
 	if err != nil {
 		return nil, fmt.Errorf("[%s]获取封面出错：%w", tag, err)
 	}
 
-	// 准备媒体数据
-	cbs, err := os.Open(thumbPath)
-	if err != nil {
-		return nil, fmt.Errorf("[%s]打开缩略图出错：%w", tag, err)
-	}
-
 	w, h, err := dovideo.GetResolution(path)
 	if err != nil {
 		return nil, fmt.Errorf("[%s]获取视频分辨率出错：%w", tag, err)
 	}
 
+	// 准备媒体数据
+	var src interface{} = OpenPath(path)
+	if local {
+		src = fmt.Sprintf("file://%s", path)
+	}
+
 	media := &InputMedia{
 		Type:              TypeVideo,
-		Media:             fmt.Sprintf("file://%s", path),
-		Thumbnail:         cbs,
+		Media:             src,
+		Thumbnail:         OpenPath(thumbPath),
+		Name:              filepath.Base(path),
 		Caption:           title,
 		Width:             w,
 		Height:            h,
@@ -51,3 +401,8 @@ func GenVideoMedia(path string, title string) (*InputMedia, error) {
 
 	return media, nil
 }
+
+// 默认的封面路径：视频同目录下的同名 .jpg
+func thumbPathOf(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".jpg"
+}
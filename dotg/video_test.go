@@ -1,6 +1,11 @@
 package dotg
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/donething/utils-go/dotg/dotgtest"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -12,3 +17,85 @@ func TestGenTgMedia(t *testing.T) {
 
 	t.Logf("%+v\n", *media)
 }
+
+func TestTGBot_MaxFileSize(t *testing.T) {
+	bot := NewTGBot("test")
+	if bot.IsLocal() || bot.MaxFileSize() != PublicFileSizeThreshold {
+		t.Errorf("TG 直连时的模式判断有误")
+	}
+
+	bot.SetAddr("http://127.0.0.1:8081")
+	if !bot.IsLocal() || bot.MaxFileSize() != FileSizeThreshold {
+		t.Errorf("本地服务时的模式判断有误")
+	}
+}
+
+func TestTGBot_sendMediaBatches(t *testing.T) {
+	bot, srv := newFakeBot(t)
+	bot.SetRateLimiter(nil)
+	srv.SetLocal(true)
+
+	dir := t.TempDir()
+	medias := make([]*InputMedia, 12)
+	paths := make([]string, 12)
+	for i := range medias {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("%02d.mp4", i))
+		if err := os.WriteFile(paths[i], []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		medias[i] = &InputMedia{Type: TypeVideo, Media: "file://" + paths[i]}
+	}
+
+	if _, err := bot.sendMediaBatches("123", medias, paths, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make([]int, 0)
+	for _, call := range srv.CallsOf("sendMediaGroup") {
+		var items []InputMedia
+		if err := json.Unmarshal([]byte(call.Form.Get("media")), &items); err != nil {
+			t.Fatal(err)
+		}
+		counts = append(counts, len(items))
+	}
+	if len(counts) != 2 || counts[0] != 10 || counts[1] != 2 {
+		t.Errorf("分批不符：%v", counts)
+	}
+}
+
+func TestVideoPipeline_plan(t *testing.T) {
+	p := &videoPipeline{
+		job:         &VideoJob{Path: "/tmp/a.mkv"},
+		segmentSize: 40,
+		report:      &VideoReport{Size: 100, Transcode: true},
+	}
+	p.plan()
+
+	if len(p.report.Segments) != 3 {
+		t.Fatalf("分段数不符：%d", len(p.report.Segments))
+	}
+	if p.report.Segments[2].Size != 20 || p.report.Segments[0].Name != "P01" {
+		t.Errorf("分段信息不符：%+v", *p.report.Segments[2])
+	}
+
+	// 不需分段、不需转码时，直接发送原视频
+	p.job.Path = "/tmp/a.mp4"
+	p.report = &VideoReport{Size: 30}
+	p.plan()
+	if len(p.report.Segments) != 1 || p.report.Segments[0].Path != "/tmp/a.mp4" {
+		t.Errorf("分段信息不符：%+v", p.report.Segments)
+	}
+}
+
+func TestTGBot_SendVideoJobReadOnly(t *testing.T) {
+	bot := NewTGBot(dotgtest.Token)
+	job := &VideoJob{Path: filepath.Join(t.TempDir(), "none.mp4"), DryRun: true}
+
+	// 不存在的视频会在获取信息时出错，但此前不应修改任务的分段大小
+	if _, err := bot.SendVideoJob(job); err == nil {
+		t.Fatal("视频不存在时应返回错误")
+	}
+	if job.SegmentSize != 0 {
+		t.Errorf("不应修改任务的分段大小，实际为 %d", job.SegmentSize)
+	}
+}
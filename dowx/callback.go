@@ -0,0 +1,340 @@
+package dowx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSignature 回调的签名不符
+	ErrSignature = errors.New("签名不符")
+	// ErrReceiverID 回调的接收者与企业 ID 不符
+	ErrReceiverID = errors.New("接收者不符")
+)
+
+// 补位的块大小。企业微信使用 32 字节，而非 AES 的 16 字节
+const cryptBlockSize = 32
+
+// MsgCrypt 企业微信回调消息的加解密
+//
+// @see https://developer.work.weixin.qq.com/document/path/90968
+type MsgCrypt struct {
+	token      string
+	receiverID string
+	key        []byte
+}
+
+// NewMsgCrypt 创建回调消息的加解密对象
+//
+// token、encodingAESKey 在应用的“接收消息”中设置
+//
+// receiverID 企业应用的回调为 corpid
+func NewMsgCrypt(token string, encodingAESKey string, receiverID string) (*MsgCrypt, error) {
+	key, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil {
+		return nil, fmt.Errorf("解码 EncodingAESKey 出错：%w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("EncodingAESKey 的长度不符：%d", len(encodingAESKey))
+	}
+
+	return &MsgCrypt{token: token, receiverID: receiverID, key: key}, nil
+}
+
+// Signature 计算签名：将 token、timestamp、nonce、encrypt 排序后拼接，再计算 SHA1
+func (c *MsgCrypt) Signature(timestamp string, nonce string, encrypt string) string {
+	strs := []string{c.token, timestamp, nonce, encrypt}
+	sort.Strings(strs)
+	sum := sha1.Sum([]byte(strings.Join(strs, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyURL 验证回调地址，返回需原样响应的明文
+func (c *MsgCrypt) VerifyURL(msgSignature string, timestamp string, nonce string, echostr string) ([]byte, error) {
+	if c.Signature(timestamp, nonce, echostr) != msgSignature {
+		return nil, ErrSignature
+	}
+
+	return c.decrypt(echostr)
+}
+
+// DecryptMsg 验证签名，并解密回调的请求体，得到消息的 XML 文本
+func (c *MsgCrypt) DecryptMsg(msgSignature string, timestamp string, nonce string, body []byte) ([]byte, error) {
+	var envelope struct {
+		ToUserName string `xml:"ToUserName"`
+		AgentID    string `xml:"AgentID"`
+		Encrypt    string `xml:"Encrypt"`
+	}
+	err := xml.Unmarshal(body, &envelope)
+	if err != nil {
+		return nil, fmt.Errorf("解析请求体出错：%w", err)
+	}
+
+	if c.Signature(timestamp, nonce, envelope.Encrypt) != msgSignature {
+		return nil, ErrSignature
+	}
+
+	return c.decrypt(envelope.Encrypt)
+}
+
+// EncryptMsg 加密被动回复的 XML 文本，返回可直接响应的 XML 文本
+func (c *MsgCrypt) EncryptMsg(msg []byte, timestamp string, nonce string) ([]byte, error) {
+	encrypt, err := c.encrypt(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := struct {
+		XMLName      xml.Name `xml:"xml"`
+		Encrypt      cdata    `xml:"Encrypt"`
+		MsgSignature cdata    `xml:"MsgSignature"`
+		TimeStamp    string   `xml:"TimeStamp"`
+		Nonce        cdata    `xml:"Nonce"`
+	}{
+		Encrypt:      cdata{encrypt},
+		MsgSignature: cdata{c.Signature(timestamp, nonce, encrypt)},
+		TimeStamp:    timestamp,
+		Nonce:        cdata{nonce},
+	}
+
+	return xml.Marshal(envelope)
+}
+
+// 解密。明文为：16 字节的随机数 + 4 字节的消息长度（大端序） + 消息 + 接收者 ID
+func (c *MsgCrypt) decrypt(encrypt string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return nil, fmt.Errorf("解码密文出错：%w", err)
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("密文的长度不符：%d", len(data))
+	}
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, c.key[:aes.BlockSize]).CryptBlocks(plain, data)
+
+	// 去除补位
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > cryptBlockSize || pad > len(plain) {
+		return nil, fmt.Errorf("补位的长度不符：%d", pad)
+	}
+	plain = plain[:len(plain)-pad]
+
+	if len(plain) < 20 {
+		return nil, fmt.Errorf("明文的长度不符：%d", len(plain))
+	}
+	n := int(binary.BigEndian.Uint32(plain[16:20]))
+	if 20+n > len(plain) {
+		return nil, fmt.Errorf("消息的长度不符：%d", n)
+	}
+
+	if string(plain[20+n:]) != c.receiverID {
+		return nil, fmt.Errorf("%w：%s", ErrReceiverID, string(plain[20+n:]))
+	}
+
+	return plain[20 : 20+n], nil
+}
+
+// 加密
+func (c *MsgCrypt) encrypt(msg []byte) (string, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 20+len(msg)+len(c.receiverID)+cryptBlockSize))
+	random := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return "", fmt.Errorf("生成随机数出错：%w", err)
+	}
+	buf.Write(random)
+	binary.Write(buf, binary.BigEndian, uint32(len(msg)))
+	buf.Write(msg)
+	buf.WriteString(c.receiverID)
+
+	// PKCS#7 补位
+	pad := cryptBlockSize - buf.Len()%cryptBlockSize
+	buf.Write(bytes.Repeat([]byte{byte(pad)}, pad))
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return "", err
+	}
+	data := buf.Bytes()
+	cipher.NewCBCEncrypter(block, c.key[:aes.BlockSize]).CryptBlocks(data, data)
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// CallbackFunc 处理回调消息。返回 nil 的回复时，不回复
+type CallbackFunc func(msg *CallbackMsg) (*Reply, error)
+
+// CallbackHandler 接收企业微信回调消息的 http.Handler
+//
+// # GET 请求验证回调地址，POST 请求解密消息后，按类型交给注册的处理函数，并加密回复
+//
+// handler := dowx.NewCallbackHandler(crypt)
+// handler.HandleText(func(msg *dowx.CallbackMsg) (*dowx.Reply, error) {...})
+// http.Handle("/wx", handler)
+type CallbackHandler struct {
+	crypt *MsgCrypt
+
+	mu       sync.RWMutex
+	text     CallbackFunc
+	events   map[string]CallbackFunc
+	fallback CallbackFunc
+}
+
+// NewCallbackHandler 创建回调消息的处理器
+func NewCallbackHandler(crypt *MsgCrypt) *CallbackHandler {
+	return &CallbackHandler{crypt: crypt, events: make(map[string]CallbackFunc)}
+}
+
+// HandleText 设置文本消息的处理函数
+func (h *CallbackHandler) HandleText(fn CallbackFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.text = fn
+}
+
+// HandleEvent 设置事件的处理函数
+//
+// event 事件类型，如 EventClick、EventTemplateCard
+func (h *CallbackHandler) HandleEvent(event string, fn CallbackFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events[event] = fn
+}
+
+// HandleDefault 设置其它消息的处理函数
+func (h *CallbackHandler) HandleDefault(fn CallbackFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.fallback = fn
+}
+
+// ServeHTTP 实现 http.Handler
+func (h *CallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	signature, timestamp, nonce := query.Get("msg_signature"), query.Get("timestamp"), query.Get("nonce")
+
+	// 验证回调地址
+	if r.Method == http.MethodGet {
+		echo, err := h.crypt.VerifyURL(signature, timestamp, nonce, query.Get("echostr"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(echo)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "不支持的请求方法", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	plain, err := h.crypt.DecryptMsg(signature, timestamp, nonce, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var msg CallbackMsg
+	err = xml.Unmarshal(plain, &msg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("解析消息出错：%s", err), http.StatusBadRequest)
+		return
+	}
+
+	fn := h.handlerOf(&msg)
+	if fn == nil {
+		return
+	}
+
+	reply, err := fn(&msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if reply == nil {
+		return
+	}
+
+	bs, err := reply.marshal(&msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	bs, err = h.crypt.EncryptMsg(bs, timestamp, nonce)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(bs)
+}
+
+// 找到消息的处理函数
+func (h *CallbackHandler) handlerOf(msg *CallbackMsg) CallbackFunc {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	switch {
+	case msg.MsgType == QYTypeText && h.text != nil:
+		return h.text
+	case msg.MsgType == CallbackEvent && h.events[msg.Event] != nil:
+		return h.events[msg.Event]
+	default:
+		return h.fallback
+	}
+}
+
+// 生成被动回复的 XML 文本
+func (r *Reply) marshal(msg *CallbackMsg) ([]byte, error) {
+	data := replyXML{
+		ToUserName:   cdata{msg.FromUserName},
+		FromUserName: cdata{msg.ToUserName},
+		CreateTime:   time.Now().Unix(),
+		MsgType:      cdata{r.MsgType},
+	}
+	switch r.MsgType {
+	case QYTypeText:
+		data.Content = &cdata{r.Content}
+	case QYTypeImage:
+		data.Image = &replyMedia{MediaID: cdata{r.MediaID}}
+	case QYTypeVoice:
+		data.Voice = &replyMedia{MediaID: cdata{r.MediaID}}
+	default:
+		return nil, fmt.Errorf("不支持回复的消息类型：%s", r.MsgType)
+	}
+
+	return xml.Marshal(data)
+}
+
+// ReplyText 生成文本回复
+func ReplyText(content string) *Reply {
+	return &Reply{MsgType: QYTypeText, Content: content}
+}
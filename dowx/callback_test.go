@@ -0,0 +1,114 @@
+package dowx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// 生成测试用的加解密对象
+func newTestCrypt(t *testing.T) *MsgCrypt {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))[:43]
+	crypt, err := NewMsgCrypt("token", key, "corpid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypt
+}
+
+func TestMsgCrypt_VerifyURL(t *testing.T) {
+	crypt := newTestCrypt(t)
+	echostr, err := crypt.encrypt([]byte("echo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := crypt.VerifyURL(crypt.Signature("1", "n", echostr), "1", "n", echostr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "echo" {
+		t.Errorf("明文不符：%s", bs)
+	}
+
+	if _, err = crypt.VerifyURL("bad", "1", "n", echostr); err != ErrSignature {
+		t.Errorf("应返回 ErrSignature，实际为 %v", err)
+	}
+
+	// 其它企业的消息
+	other, _ := NewMsgCrypt("token", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))[:43], "other")
+	echostr, _ = other.encrypt([]byte("echo"))
+	if _, err = crypt.VerifyURL(crypt.Signature("1", "n", echostr), "1", "n", echostr); err == nil {
+		t.Errorf("应返回 ErrReceiverID")
+	}
+}
+
+func TestCallbackHandler_ServeHTTP(t *testing.T) {
+	crypt := newTestCrypt(t)
+	handler := NewCallbackHandler(crypt)
+	handler.HandleText(func(msg *CallbackMsg) (*Reply, error) {
+		return ReplyText("收到：" + msg.Content), nil
+	})
+	var clicked string
+	handler.HandleEvent(EventClick, func(msg *CallbackMsg) (*Reply, error) {
+		clicked = msg.EventKey
+		return nil, nil
+	})
+
+	// 加密消息，生成请求
+	post := func(plain string) *httptest.ResponseRecorder {
+		encrypt, err := crypt.encrypt([]byte(plain))
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := url.Values{
+			"msg_signature": {crypt.Signature("1", "n", encrypt)},
+			"timestamp":     {"1"},
+			"nonce":         {"n"},
+		}
+		body := fmt.Sprintf("<xml><ToUserName><![CDATA[corpid]]></ToUserName>"+
+			"<Encrypt><![CDATA[%s]]></Encrypt></xml>", encrypt)
+		req := httptest.NewRequest(http.MethodPost, "/wx?"+query.Encode(), bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 文本消息，回复
+	w := post("<xml><ToUserName>corpid</ToUserName><FromUserName>user</FromUserName>" +
+		"<MsgType>text</MsgType><Content>你好</Content></xml>")
+	if w.Code != http.StatusOK {
+		t.Fatalf("响应码不符：%d %s", w.Code, w.Body)
+	}
+	var envelope struct {
+		Encrypt      string `xml:"Encrypt"`
+		MsgSignature string `xml:"MsgSignature"`
+		TimeStamp    string `xml:"TimeStamp"`
+		Nonce        string `xml:"Nonce"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	plain, err := crypt.DecryptMsg(envelope.MsgSignature, envelope.TimeStamp, envelope.Nonce, w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply CallbackMsg
+	if err = xml.Unmarshal(plain, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Content != "收到：你好" || reply.ToUserName != "user" || reply.FromUserName != "corpid" {
+		t.Errorf("回复不符：%s", plain)
+	}
+
+	// 事件，不回复
+	w = post("<xml><MsgType>event</MsgType><Event>click</Event><EventKey>k1</EventKey></xml>")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || clicked != "k1" {
+		t.Errorf("处理事件不符：%d %s %s", w.Code, w.Body, clicked)
+	}
+}
@@ -0,0 +1,315 @@
+package dowx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 通讯录
+	qyDepartmentListURL = "/cgi-bin/department/list?access_token=%s"
+	qyUserSimpleListURL = "/cgi-bin/user/simplelist?access_token=%s"
+	qyTagListURL        = "/cgi-bin/tag/list?access_token=%s"
+	qyTagUsersURL       = "/cgi-bin/tag/get?access_token=%s"
+)
+
+// QYMessage 企业消息。QYMsgText 等嵌入了 *QYMsg 的消息都实现了该接口
+type QYMessage interface {
+	Base() *QYMsg
+}
+
+// Base 消息的公共部分
+func (m *QYMsg) Base() *QYMsg {
+	return m
+}
+
+// Recipient 企业消息的接收者，可同时指定成员、部门、标签
+//
+// to := dowx.NewRecipient().Users("zhangsan", "lisi").Parties(2).Tags(1)
+type Recipient struct {
+	users   []string
+	parties []string
+	tags    []string
+}
+
+// NewRecipient 创建接收者
+func NewRecipient() *Recipient {
+	return &Recipient{}
+}
+
+// ToAll 所有成员
+func ToAll() *Recipient {
+	return NewRecipient().Users("@all")
+}
+
+// Users 添加成员的 userid
+func (r *Recipient) Users(userids ...string) *Recipient {
+	r.users = append(r.users, userids...)
+	return r
+}
+
+// Parties 添加部门 ID
+func (r *Recipient) Parties(ids ...int) *Recipient {
+	for _, id := range ids {
+		r.parties = append(r.parties, strconv.Itoa(id))
+	}
+	return r
+}
+
+// Tags 添加标签 ID
+func (r *Recipient) Tags(ids ...int) *Recipient {
+	for _, id := range ids {
+		r.tags = append(r.tags, strconv.Itoa(id))
+	}
+	return r
+}
+
+// 设置消息的接收者
+func (r *Recipient) apply(msg *QYMsg) {
+	msg.Touser = strings.Join(r.users, "|")
+	msg.Toparty = strings.Join(r.parties, "|")
+	msg.Totag = strings.Join(r.tags, "|")
+}
+
+// PartialError 部分接收者无效（如不存在、不在应用的可见范围），消息已推送给其它接收者
+type PartialError struct {
+	Users   []string
+	Parties []string
+	Tags    []string
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("部分接收者无效：成员 %v，部门 %v，标签 %v", e.Users, e.Parties, e.Tags)
+}
+
+// IsPartialError 是否为部分接收者无效的错误
+func IsPartialError(err error) bool {
+	var e *PartialError
+	return errors.As(err, &e)
+}
+
+// 根据推送的结果生成 *PartialError。所有接收者都有效时返回 nil
+func partialError(result *PushResult) error {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, "|")
+	}
+
+	e := &PartialError{
+		Users:   split(result.Invaliduser),
+		Parties: split(result.Invalidparty),
+		Tags:    split(result.Invalidtag),
+	}
+	if len(e.Users) == 0 && len(e.Parties) == 0 && len(e.Tags) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// Departments 获取部门及其子部门
+//
+// id 部门 ID，为 0 时获取全部部门
+func (q *QiYe) Departments(id int) ([]Department, error) {
+	query := url.Values{}
+	if id != 0 {
+		query.Set("id", strconv.Itoa(id))
+	}
+
+	var result struct {
+		Department []Department `json:"department"`
+	}
+	err := q.Core.call(qyTokenURL, qyDepartmentListURL, query, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取部门列表出错：%w", err)
+	}
+
+	return result.Department, nil
+}
+
+// DepartmentUsers 获取部门的成员
+//
+// fetchChild 是否递归获取子部门的成员
+func (q *QiYe) DepartmentUsers(departmentID int, fetchChild bool) ([]QYUser, error) {
+	query := url.Values{"department_id": {strconv.Itoa(departmentID)}, "fetch_child": {"0"}}
+	if fetchChild {
+		query.Set("fetch_child", "1")
+	}
+
+	var result struct {
+		Userlist []QYUser `json:"userlist"`
+	}
+	err := q.Core.call(qyTokenURL, qyUserSimpleListURL, query, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取部门'%d'的成员出错：%w", departmentID, err)
+	}
+
+	return result.Userlist, nil
+}
+
+// Tags 获取标签列表
+func (q *QiYe) Tags() ([]QYTag, error) {
+	var result struct {
+		Taglist []QYTag `json:"taglist"`
+	}
+	err := q.Core.call(qyTokenURL, qyTagListURL, nil, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取标签列表出错：%w", err)
+	}
+
+	return result.Taglist, nil
+}
+
+// TagMembers 获取标签下的成员、部门
+func (q *QiYe) TagMembers(tagID int) ([]QYUser, []int, error) {
+	var result struct {
+		Userlist  []QYUser `json:"userlist"`
+		Partylist []int    `json:"partylist"`
+	}
+	err := q.Core.call(qyTokenURL, qyTagUsersURL, url.Values{"tagid": {strconv.Itoa(tagID)}}, nil, &result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取标签'%d'的成员出错：%w", tagID, err)
+	}
+
+	return result.Userlist, result.Partylist, nil
+}
+
+// Directory 企业的通讯录，可缓存到本地文件，以便按名称查找接收者
+type Directory struct {
+	Departments []Department `json:"departments"`
+	Users       []QYUser     `json:"users"`
+	Tags        []QYTag      `json:"tags"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// LoadDirectory 获取通讯录
+//
+// path 本地缓存文件的路径。为空""时不缓存
+//
+// maxAge 缓存的有效期。缓存已过期或不存在时，将重新获取并保存
+func (q *QiYe) LoadDirectory(path string, maxAge time.Duration) (*Directory, error) {
+	if path != "" {
+		bs, err := os.ReadFile(path)
+		if err == nil {
+			var dir Directory
+			if err = json.Unmarshal(bs, &dir); err == nil && time.Since(dir.UpdatedAt) < maxAge {
+				return &dir, nil
+			}
+		}
+	}
+
+	dir, err := q.fetchDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		bs, err := json.MarshalIndent(dir, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("序列化通讯录出错：%w", err)
+		}
+		err = os.WriteFile(path, bs, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("保存通讯录出错：%w", err)
+		}
+	}
+
+	return dir, nil
+}
+
+// 获取所有部门、成员、标签
+func (q *QiYe) fetchDirectory() (*Directory, error) {
+	departments, err := q.Departments(0)
+	if err != nil {
+		return nil, err
+	}
+
+	// 从根部门递归获取所有成员
+	users := make([]QYUser, 0)
+	if len(departments) != 0 {
+		root := departments[0]
+		for _, d := range departments {
+			if d.Parentid == 0 {
+				root = d
+				break
+			}
+		}
+		users, err = q.DepartmentUsers(root.ID, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tags, err := q.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Directory{Departments: departments, Users: users, Tags: tags, UpdatedAt: time.Now()}, nil
+}
+
+// FindUser 根据姓名查找成员。不存在时返回 nil
+func (d *Directory) FindUser(name string) *QYUser {
+	for i := range d.Users {
+		if d.Users[i].Name == name {
+			return &d.Users[i]
+		}
+	}
+	return nil
+}
+
+// FindDepartment 根据名称查找部门。不存在时返回 nil
+func (d *Directory) FindDepartment(name string) *Department {
+	for i := range d.Departments {
+		if d.Departments[i].Name == name {
+			return &d.Departments[i]
+		}
+	}
+	return nil
+}
+
+// FindTag 根据名称查找标签。不存在时返回 nil
+func (d *Directory) FindTag(name string) *QYTag {
+	for i := range d.Tags {
+		if d.Tags[i].Tagname == name {
+			return &d.Tags[i]
+		}
+	}
+	return nil
+}
+
+// Recipient 根据成员姓名、部门名称、标签名称生成接收者。有名称不存在时返回错误
+func (d *Directory) Recipient(users []string, parties []string, tags []string) (*Recipient, error) {
+	to := NewRecipient()
+	for _, name := range users {
+		u := d.FindUser(name)
+		if u == nil {
+			return nil, fmt.Errorf("通讯录中不存在成员'%s'", name)
+		}
+		to.Users(u.Userid)
+	}
+	for _, name := range parties {
+		p := d.FindDepartment(name)
+		if p == nil {
+			return nil, fmt.Errorf("通讯录中不存在部门'%s'", name)
+		}
+		to.Parties(p.ID)
+	}
+	for _, name := range tags {
+		t := d.FindTag(name)
+		if t == nil {
+			return nil, fmt.Errorf("通讯录中不存在标签'%s'", name)
+		}
+		to.Tags(t.Tagid)
+	}
+
+	return to, nil
+}
@@ -0,0 +1,80 @@
+package dowx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRecipient_apply(t *testing.T) {
+	msg := QYMsgText{QYMsg: &QYMsg{Msgtype: QYTypeText}}
+	NewRecipient().Users("a", "b").Parties(2).Tags(1, 3).apply(msg.Base())
+
+	if msg.Touser != "a|b" || msg.Toparty != "2" || msg.Totag != "1|3" {
+		t.Errorf("接收者不符：%+v", *msg.QYMsg)
+	}
+}
+
+func TestQiYe_PushTo(t *testing.T) {
+	qy, srv := newFakeQiYe(t)
+
+	// 缺少公共部分、接收者时返回错误，而不是崩溃
+	if _, err := qy.PushTo(NewRecipient().Users("a"), &QYMsgText{Text: QYMsgItemText{Content: "测试"}}); err == nil {
+		t.Error("消息缺少 *QYMsg 时应返回错误")
+	}
+	msg := &QYMsgText{QYMsg: &QYMsg{Agentid: aid, Msgtype: QYTypeText}, Text: QYMsgItemText{Content: "测试"}}
+	if _, err := qy.PushTo(nil, msg); err == nil {
+		t.Error("没有接收者时应返回错误")
+	}
+	if len(srv.Messages()) != 0 {
+		t.Fatalf("出错时不应推送，实际推送 %d 条", len(srv.Messages()))
+	}
+
+	if _, err := qy.PushTo(NewRecipient().Users("a").Tags(1), msg); err != nil {
+		t.Fatal(err)
+	}
+	var sent QYMsgText
+	if err := srv.Messages()[0].Decode(&sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Touser != "a" || sent.Totag != "1" || sent.Text.Content != "测试" {
+		t.Errorf("推送的消息不符：%+v", sent)
+	}
+}
+
+func TestPartialError(t *testing.T) {
+	if err := partialError(&PushResult{}); err != nil {
+		t.Errorf("应返回 nil，实际为 %v", err)
+	}
+
+	err := fmt.Errorf("推送出错：%w", partialError(&PushResult{Invaliduser: "a|b", Invalidtag: "3"}))
+	var e *PartialError
+	if !IsPartialError(err) || !errors.As(err, &e) {
+		t.Fatalf("应返回 *PartialError，实际为 %v", err)
+	}
+	if len(e.Users) != 2 || e.Users[1] != "b" || len(e.Parties) != 0 || e.Tags[0] != "3" {
+		t.Errorf("无效的接收者不符：%+v", *e)
+	}
+}
+
+func TestDirectory_Recipient(t *testing.T) {
+	dir := &Directory{
+		Departments: []Department{{ID: 1, Name: "总部"}, {ID: 2, Name: "研发部", Parentid: 1}},
+		Users:       []QYUser{{Userid: "zhangsan", Name: "张三"}},
+		Tags:        []QYTag{{Tagid: 5, Tagname: "值班"}},
+	}
+
+	to, err := dir.Recipient([]string{"张三"}, []string{"研发部"}, []string{"值班"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg QYMsg
+	to.apply(&msg)
+	if msg.Touser != "zhangsan" || msg.Toparty != "2" || msg.Totag != "5" {
+		t.Errorf("接收者不符：%+v", msg)
+	}
+
+	if _, err = dir.Recipient([]string{"李四"}, nil, nil); err == nil {
+		t.Errorf("不存在的成员应返回错误")
+	}
+}
@@ -1,79 +1,329 @@
 package dowx
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/donething/utils-go/dohttp"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
 // Core 微信消息推送的核心信息
+//
+// 可在多个协程中使用
 type Core struct {
-	appid   string    // 测试号的 appid
-	secret  string    // 测试号的 secret
-	token   string    // 根据 appid、secret 获取推送消息需要的 token
-	expires time.Time // token 的过期时间，以重复利用
+	appid  string // 测试号的 appid
+	secret string // 测试号的 secret
+
+	// 接口地址，如 QYAddr、MPAddr
+	addr string
+
+	// 缓存根据 appid、secret 获取的 token，以重复利用
+	store TokenStore
+
+	// 正在进行的刷新 token，同时需要刷新时只请求一次
+	mu     sync.Mutex
+	flight *tokenCall
+}
+
+// 一次刷新 token 的请求
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
 }
 
 var client = dohttp.New(false, false)
 
-// 获取 token
-func (c *Core) getToken(url string) error {
-	// 如果 token 已存在且未过期，则继续使用，不重新获取
-	if c.token != "" && time.Now().Before(c.expires) {
-		return nil
-	}
+// 创建 Core，默认在内存中缓存 token
+func newCore(addr string, appid string, secret string) *Core {
+	return &Core{addr: addr, appid: appid, secret: secret, store: NewMemoryTokenStore()}
+}
 
-	// 需要重新获取
+// SetAddr 设置接口地址，如代理服务、测试用的模拟服务（dowxtest.Server）的地址
+//
+// addr 如 "http://127.0.0.1:12345"，末尾不含"/"
+func (c *Core) SetAddr(addr string) {
+	c.addr = addr
+}
+
+// SetTokenStore 设置 token 的缓存。多个进程共用同一个应用时，可用 FileTokenStore 共享 token，
+// 以免每次重启都重新获取（微信限制了每天获取 token 的次数）
+func (c *Core) SetTokenStore(store TokenStore) {
+	c.store = store
+}
+
+// 在缓存中的键。不同 secret 的 token 不同，如企业微信的不同应用
+func (c *Core) tokenKey() string {
+	sum := sha1.Sum([]byte(c.secret))
+	return c.appid + "_" + hex.EncodeToString(sum[:4])
+}
+
+// 获取 token
+func (c *Core) getToken(url string) (*Token, error) {
 	bs, err := client.GetBytes(url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 解析并获取 token
 	var token tokenResult
 	err = json.Unmarshal(bs, &token)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if token.AccessToken == "" {
-		return fmt.Errorf("无法从文本中获取到 token: %s", string(bs))
+		return nil, fmt.Errorf("无法从文本中获取到 token: %s", string(bs))
 	}
 
-	// 获取 token 成功，设置 token 和 expires
-	c.token = token.AccessToken
 	// 将 token 过期时间减少 3 分钟，以容错
-	duration, err := time.ParseDuration(fmt.Sprintf("%ds", token.ExpiresIn-180))
+	return &Token{
+		AccessToken: token.AccessToken,
+		Expires:     time.Now().Add(time.Duration(token.ExpiresIn-180) * time.Second),
+	}, nil
+}
+
+// 获取有效的 token。缓存中不存在或已过期时，将重新获取
+func (c *Core) accessToken(tokenURL string) (string, error) {
+	token, err := c.store.Get(c.tokenKey())
+	if err == nil && token.Valid() {
+		return token.AccessToken, nil
+	}
+
+	return c.refreshToken(tokenURL, "")
+}
+
+// 重新获取 token。同时有多个刷新时，只请求一次，其它的等待并共用其结果
+//
+// stale 已失效的 token。缓存中的 token 有效且与之不同时（已被其它协程、进程刷新），直接使用
+func (c *Core) refreshToken(tokenURL string, stale string) (string, error) {
+	c.mu.Lock()
+	if call := c.flight; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.token, call.err
+	}
+
+	token, err := c.store.Get(c.tokenKey())
+	if err == nil && token.Valid() && token.AccessToken != stale {
+		c.mu.Unlock()
+		return token.AccessToken, nil
+	}
+
+	call := &tokenCall{done: make(chan struct{})}
+	c.flight = call
+	c.mu.Unlock()
+
+	call.token, call.err = c.fetchToken(tokenURL)
+
+	c.mu.Lock()
+	c.flight = nil
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.token, call.err
+}
+
+// 请求新的 token，并保存到缓存
+func (c *Core) fetchToken(tokenURL string) (string, error) {
+	token, err := c.getToken(c.addr + fmt.Sprintf(tokenURL, url.QueryEscape(c.appid), url.QueryEscape(c.secret)))
+	if err != nil {
+		return "", fmt.Errorf("获取 token 出错：%w", err)
+	}
+
+	err = c.store.Set(c.tokenKey(), token)
+	if err != nil {
+		return "", fmt.Errorf("缓存 token 出错：%w", err)
+	}
+
+	return token.AccessToken, nil
+}
+
+// 使用 token 执行请求，返回响应内容。token 无效或已过期时，将刷新 token 后重试一次
+//
+// do 执行请求的函数
+func (c *Core) withToken(tokenURL string, do func(token string) ([]byte, error)) ([]byte, error) {
+	token, err := c.accessToken(tokenURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; ; i++ {
+		bs, err := do(token)
+		if err != nil {
+			return nil, err
+		}
+
+		// 判断 token 是否有效
+		var result PushResult
+		err = json.Unmarshal(bs, &result)
+		if err != nil {
+			return nil, fmt.Errorf("解析响应 JSON 文本时出错：%w", err)
+		}
+		if i > 0 || !isTokenErr(result.Errcode) {
+			return bs, nil
+		}
+
+		token, err = c.refreshToken(tokenURL, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// 推送消息，返回推送的结果
+func (c *Core) push(tokenURL string, sendURL string, data interface{}) (*PushResult, error) {
+	var result PushResult
+	err := c.call(tokenURL, sendURL, nil, data, &result)
+	if err != nil {
+		return nil, fmt.Errorf("推送时出错：%w", err)
+	}
+
+	return &result, nil
+}
+
+// 调用接口，并将响应解析到 result。result 为 nil 时不解析
+//
+// apiURL 接口的路径，其中的"%s"将被替换为 token
+//
+// query 追加到地址后的其它参数，可为 nil
+//
+// data 为 nil 时发送 GET 请求，否则 POST 其 JSON 文本
+func (c *Core) call(tokenURL string, apiURL string, query url.Values, data interface{}, result interface{}) error {
+	bs, err := c.withToken(tokenURL, func(token string) ([]byte, error) {
+		u := c.addr + fmt.Sprintf(apiURL, token)
+		if len(query) != 0 {
+			u += "&" + query.Encode()
+		}
+
+		var bs []byte
+		var err error
+		if data == nil {
+			bs, err = client.GetBytes(u, nil)
+		} else {
+			bs, err = client.PostJSONObj(u, data, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("请求时网络出错：%w", err)
+		}
+		return bs, nil
+	})
 	if err != nil {
 		return err
 	}
-	c.expires = time.Now().Add(duration)
+
+	// 解析、判断请求的结果
+	var errResult PushResult
+	err = json.Unmarshal(bs, &errResult)
+	if err != nil {
+		return fmt.Errorf("解析响应 JSON 文本时出错：%w", err)
+	}
+	if errResult.Errcode != 0 {
+		return fmt.Errorf("接口返回错误：%s", string(bs))
+	}
+
+	if result == nil {
+		return nil
+	}
+	err = json.Unmarshal(bs, result)
+	if err != nil {
+		return fmt.Errorf("解析响应 JSON 文本时出错：%w", err)
+	}
+
 	return nil
 }
 
-// 推送消息
-func (c *Core) push(tokenURL string, sendURL string, data interface{}) error {
-	// 获取、更新 token
-	err := c.getToken(fmt.Sprintf(tokenURL, c.appid, c.secret))
+// 上传文件，并将响应解析到 result
+//
+// uploadURL 上传的路径，其中的"%s"将被替换为 token
+//
+// field 文件的表单名，name 上传后的文件名，为空""时使用路径中的文件名
+func (c *Core) upload(tokenURL string, uploadURL string, field string, path string, name string,
+	result interface{}) error {
+	bs, err := c.withToken(tokenURL, func(token string) ([]byte, error) {
+		bs, err := postFile(c.addr+fmt.Sprintf(uploadURL, token), field, path, name)
+		if err != nil {
+			return nil, fmt.Errorf("上传文件时网络出错：%w", err)
+		}
+		return bs, nil
+	})
 	if err != nil {
-		return fmt.Errorf("获取 token 出错：%w", err)
+		return err
 	}
 
-	// 推送(post)的数据
-	bs, err := client.PostJSONObj(fmt.Sprintf(sendURL, c.token), data, nil)
+	// 先判断是否出错，再解析结果
+	var errResult PushResult
+	err = json.Unmarshal(bs, &errResult)
 	if err != nil {
-		return fmt.Errorf("推送消息时网络出错：%w", err)
+		return fmt.Errorf("解析上传响应 JSON 文本时出错：%w", err)
+	}
+	if errResult.Errcode != 0 {
+		return fmt.Errorf("上传时出错：%s", string(bs))
 	}
 
-	// 解析、判断推送的结果
-	var result PushResult
-	err = json.Unmarshal(bs, &result)
+	err = json.Unmarshal(bs, result)
 	if err != nil {
-		return fmt.Errorf("解析推送响应 JSON 文本时出错：%w", err)
-	}
-	if result.Errcode != 0 {
-		return fmt.Errorf("推送时出错：%s", string(bs))
+		return fmt.Errorf("解析上传响应 JSON 文本时出错：%w", err)
 	}
 
 	return nil
 }
+
+// 是否为 token 无效、已过期的错误码
+func isTokenErr(errcode int) bool {
+	switch errcode {
+	case 40001, 40014, 42001:
+		return true
+	default:
+		return false
+	}
+}
+
+// 以 multipart 表单上传文件，并读取响应
+//
+// name 表单中的文件名，为空""时使用路径中的文件名
+func postFile(u string, field string, path string, name string) ([]byte, error) {
+	if name == "" {
+		name = filepath.Base(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fw, err := writer.CreateFormFile(field, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(fw, file); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u, &body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Exec(req, map[string]string{"Content-Type": writer.FormDataContentType()})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
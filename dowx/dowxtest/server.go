@@ -0,0 +1,401 @@
+// Package dowxtest 进程内的微信、企业微信接口模拟服务，用于离线测试 dowx
+//
+// 实现了获取 token、企业消息推送、撤回、上传临时素材、模板消息推送、获取关注者列表的接口，
+// 可模拟 token 过期、速率限制、无效的接收者等错误
+//
+// srv := dowxtest.NewServer()
+// defer srv.Close()
+// qy := dowx.NewQiYe("corpid", "secret")
+// qy.SetAddr(srv.URL)
+package dowxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// 常用的错误码
+const (
+	// ErrcodeInvalidCredential secret 错误
+	ErrcodeInvalidCredential = 40001
+	// ErrcodeInvalidToken token 无效
+	ErrcodeInvalidToken = 40014
+	// ErrcodeTokenExpired token 已过期
+	ErrcodeTokenExpired = 42001
+	// ErrcodeRateLimit 超过调用频率限制
+	ErrcodeRateLimit = 45009
+	// ErrcodeInvalidUser 所有接收者都无效
+	ErrcodeInvalidUser = 81013
+	// ErrcodeInvalidParam 参数无效。如撤回不存在、已撤回的消息，上传时没有文件
+	ErrcodeInvalidParam = 40058
+)
+
+// 实现的接口的路径
+const (
+	PathQYToken      = "/cgi-bin/gettoken"
+	PathMPToken      = "/cgi-bin/token"
+	PathMessageSend  = "/cgi-bin/message/send"
+	PathTemplateSend = "/cgi-bin/message/template/send"
+	PathRecall       = "/cgi-bin/message/recall"
+	PathMediaUpload  = "/cgi-bin/media/upload"
+	// 公众号获取关注者列表
+	PathUserGet = "/cgi-bin/user/get"
+)
+
+// 获取关注者列表时，每页的默认数量
+const defaultPageSize = 10000
+
+// Message 收到的推送请求
+type Message struct {
+	// 接口的路径，如 PathMessageSend
+	Path string
+	// 使用的 token
+	Token string
+	// 推送的 JSON 文本
+	Body []byte
+}
+
+// Decode 将推送的 JSON 文本解析到 v
+func (m *Message) Decode(v interface{}) error {
+	return json.Unmarshal(m.Body, v)
+}
+
+// Media 上传的临时素材
+type Media struct {
+	// 分配的素材 ID
+	MediaID string
+	// 素材类型，如"file"
+	Type string
+	// 文件名
+	Filename string
+	// 文件的内容
+	Content []byte
+}
+
+// Server 模拟的微信接口服务
+type Server struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// 接受的 appid、corpid 对应的 secret。为空时接受任意的凭证
+	credentials map[string]string
+	// 已颁发的有效 token
+	tokens  map[string]bool
+	fetched int
+	// 各接口待返回的错误码，依次返回
+	failures map[string][]int
+	// 无效的接收者
+	invalidUsers map[string]bool
+	messages     []*Message
+	msgid        int64
+	// 可撤回的企业消息的 ID，及已撤回的消息 ID
+	sent     map[string]bool
+	recalled []string
+	uploads  []*Media
+	// 公众号的关注者，及获取时每页的数量
+	followers []string
+	pageSize  int
+}
+
+// NewServer 创建并启动模拟服务。用完需调用 Close()
+func NewServer() *Server {
+	s := &Server{
+		credentials:  make(map[string]string),
+		tokens:       make(map[string]bool),
+		failures:     make(map[string][]int),
+		invalidUsers: make(map[string]bool),
+		sent:         make(map[string]bool),
+		pageSize:     defaultPageSize,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+// AddCredential 只接受已添加的凭证，其它凭证获取 token 时返回 ErrcodeInvalidCredential
+func (s *Server) AddCredential(appid string, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.credentials[appid] = secret
+}
+
+// ExpireTokens 使已颁发的 token 全部过期，之后使用它们时返回 ErrcodeTokenExpired
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = make(map[string]bool)
+}
+
+// TokenFetches 获取 token 的次数
+func (s *Server) TokenFetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fetched
+}
+
+// Fail 使接下来 times 次调用 path 时，返回错误码 errcode。如 Fail(PathMessageSend, 1, ErrcodeRateLimit)
+func (s *Server) Fail(path string, times int, errcode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < times; i++ {
+		s.failures[path] = append(s.failures[path], errcode)
+	}
+}
+
+// InvalidUsers 将 userid 设为无效的接收者。推送时将在结果的 invaliduser 中返回；若所有接收者都无效，返回 ErrcodeInvalidUser
+func (s *Server) InvalidUsers(userids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range userids {
+		s.invalidUsers[id] = true
+	}
+}
+
+// Messages 获取收到的推送请求
+func (s *Server) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Message(nil), s.messages...)
+}
+
+// Recalled 获取已撤回的消息 ID
+func (s *Server) Recalled() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.recalled...)
+}
+
+// Uploads 获取上传的临时素材
+func (s *Server) Uploads() []*Media {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Media(nil), s.uploads...)
+}
+
+// AddFollowers 添加公众号的关注者
+func (s *Server) AddFollowers(openids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.followers = append(s.followers, openids...)
+}
+
+// SetPageSize 设置获取关注者列表时每页的数量，以便测试翻页。小于等于 0 时恢复为默认的 10000
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n <= 0 {
+		n = defaultPageSize
+	}
+	s.pageSize = n
+}
+
+// 处理请求
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query()
+	switch r.URL.Path {
+	case PathQYToken:
+		s.issueToken(w, r.URL.Path, query.Get("corpid"), query.Get("corpsecret"))
+		return
+	case PathMPToken:
+		s.issueToken(w, r.URL.Path, query.Get("appid"), query.Get("secret"))
+		return
+	case PathMessageSend, PathTemplateSend, PathRecall, PathMediaUpload, PathUserGet:
+	default:
+		writeJSON(w, map[string]interface{}{"errcode": 404, "errmsg": "not found: " + r.URL.Path})
+		return
+	}
+
+	token := query.Get("access_token")
+	if !s.tokens[token] {
+		errcode := ErrcodeInvalidToken
+		if strings.HasPrefix(token, "token") {
+			errcode = ErrcodeTokenExpired
+		}
+		writeErr(w, errcode)
+		return
+	}
+
+	if codes := s.failures[r.URL.Path]; len(codes) != 0 {
+		s.failures[r.URL.Path] = codes[1:]
+		writeErr(w, codes[0])
+		return
+	}
+
+	switch r.URL.Path {
+	case PathMediaUpload:
+		s.upload(w, r, query.Get("type"))
+		return
+	case PathUserGet:
+		s.listFollowers(w, query.Get("next_openid"))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeErr(w, 44002)
+		return
+	}
+	if r.URL.Path == PathRecall {
+		s.recall(w, body)
+		return
+	}
+
+	s.messages = append(s.messages, &Message{Path: r.URL.Path, Token: token, Body: body})
+	s.msgid++
+
+	// 模板消息返回数字形式的消息 ID
+	if r.URL.Path == PathTemplateSend {
+		writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "msgid": s.msgid})
+		return
+	}
+
+	// 企业消息，检查接收者
+	var msg struct {
+		Touser string `json:"touser"`
+	}
+	json.Unmarshal(body, &msg)
+	invalid := make([]string, 0)
+	users := strings.Split(msg.Touser, "|")
+	for _, u := range users {
+		if s.invalidUsers[u] {
+			invalid = append(invalid, u)
+		}
+	}
+	if len(invalid) != 0 && len(invalid) == len(users) {
+		writeErr(w, ErrcodeInvalidUser)
+		return
+	}
+
+	msgid := fmt.Sprintf("msg%d", s.msgid)
+	s.sent[msgid] = true
+	writeJSON(w, map[string]interface{}{
+		"errcode":     0,
+		"errmsg":      "ok",
+		"msgid":       msgid,
+		"invaliduser": strings.Join(invalid, "|"),
+	})
+}
+
+// 撤回企业消息。只能撤回已推送、且未撤回的消息
+func (s *Server) recall(w http.ResponseWriter, body []byte) {
+	var req struct {
+		Msgid string `json:"msgid"`
+	}
+	json.Unmarshal(body, &req)
+	if !s.sent[req.Msgid] {
+		writeErr(w, ErrcodeInvalidParam)
+		return
+	}
+
+	delete(s.sent, req.Msgid)
+	s.recalled = append(s.recalled, req.Msgid)
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+}
+
+// 上传临时素材。文件的表单名为"media"
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, mediaType string) {
+	file, header, err := r.FormFile("media")
+	if err != nil {
+		writeErr(w, ErrcodeInvalidParam)
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		writeErr(w, 44002)
+		return
+	}
+
+	media := &Media{
+		MediaID:  fmt.Sprintf("media%d", len(s.uploads)+1),
+		Type:     mediaType,
+		Filename: header.Filename,
+		Content:  content,
+	}
+	s.uploads = append(s.uploads, media)
+	writeJSON(w, map[string]interface{}{
+		"errcode":    0,
+		"errmsg":     "ok",
+		"type":       media.Type,
+		"media_id":   media.MediaID,
+		"created_at": "1380000000",
+	})
+}
+
+// 获取关注者列表。从 nextOpenid 之后开始，获取完后 count 为 0
+func (s *Server) listFollowers(w http.ResponseWriter, nextOpenid string) {
+	start := 0
+	if nextOpenid != "" {
+		for i, id := range s.followers {
+			if id == nextOpenid {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := start + s.pageSize
+	if end > len(s.followers) {
+		end = len(s.followers)
+	}
+
+	page := append([]string{}, s.followers[start:end]...)
+	next := ""
+	if len(page) != 0 {
+		next = page[len(page)-1]
+	}
+	writeJSON(w, map[string]interface{}{
+		"total":       len(s.followers),
+		"count":       len(page),
+		"data":        map[string]interface{}{"openid": page},
+		"next_openid": next,
+	})
+}
+
+// 颁发 token
+func (s *Server) issueToken(w http.ResponseWriter, path string, appid string, secret string) {
+	if len(s.credentials) != 0 && (s.credentials[appid] != secret || secret == "") {
+		writeErr(w, ErrcodeInvalidCredential)
+		return
+	}
+
+	if codes := s.failures[path]; len(codes) != 0 {
+		s.failures[path] = codes[1:]
+		writeErr(w, codes[0])
+		return
+	}
+
+	s.fetched++
+	token := fmt.Sprintf("token%d", s.fetched)
+	s.tokens[token] = true
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "access_token": token, "expires_in": 7200})
+}
+
+// 响应错误码
+func writeErr(w http.ResponseWriter, errcode int) {
+	writeJSON(w, map[string]interface{}{"errcode": errcode, "errmsg": fmt.Sprintf("fake error %d", errcode)})
+}
+
+// 响应 JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
@@ -1,5 +1,10 @@
 package dowx
 
+import (
+	"encoding/json"
+	"encoding/xml"
+)
+
 // 请求的相应
 
 // 获取 token 的结果
@@ -13,9 +18,9 @@ type tokenResult struct {
 // PushResult 推送消息的响应
 type PushResult struct {
 	// 通用部分
-	Errcode int         `json:"errcode"`
-	Errmsg  string      `json:"errmsg"`
-	Msgid   interface{} `json:"msgid"` // 消息 ID，企业消息返回 数字，测试号消息返回 字符串
+	Errcode int    `json:"errcode"`
+	Errmsg  string `json:"errmsg"`
+	Msgid   MsgID  `json:"msgid"` // 消息 ID，可用于撤回企业消息
 
 	// 企业微信部分
 	Invaliduser  string `json:"invaliduser"`
@@ -24,6 +29,31 @@ type PushResult struct {
 	ResponseCode string `json:"response_code"`
 }
 
+// MsgID 消息 ID。企业消息返回字符串，公众号、测试号消息返回数字，统一保存为字符串
+type MsgID string
+
+// UnmarshalJSON 同时支持数字、字符串形式的消息 ID。为 null 时不修改
+func (id *MsgID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) != 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = MsgID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = MsgID(n)
+	return nil
+}
+
 // 微信测试号消息推送
 
 // SBMsgItem 微信测试号消息的项
@@ -94,3 +124,500 @@ type QYMsgMarkdown struct {
 	*QYMsg
 	Markdown QYMsgItemText `json:"markdown"`
 }
+
+// 企业微信的消息类型
+const (
+	QYTypeText         = "text"
+	QYTypeImage        = "image"
+	QYTypeVoice        = "voice"
+	QYTypeVideo        = "video"
+	QYTypeFile         = "file"
+	QYTypeTextcard     = "textcard"
+	QYTypeNews         = "news"
+	QYTypeMpnews       = "mpnews"
+	QYTypeMarkdown     = "markdown"
+	QYTypeTemplateCard = "template_card"
+	QYTypeInteractive  = "interactive_taskcard"
+)
+
+// 企业微信临时素材的类型
+const (
+	MediaImage = "image"
+	MediaVoice = "voice"
+	MediaVideo = "video"
+	MediaFile  = "file"
+)
+
+// MediaResult 上传临时素材的响应
+type MediaResult struct {
+	Type      string `json:"type"`
+	MediaID   string `json:"media_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// 上传图片的响应
+type uploadImgResult struct {
+	Url string `json:"url"`
+}
+
+// QYMsgItemMedia 企业图片、语音、文件消息
+type QYMsgItemMedia struct {
+	MediaID string `json:"media_id"` // 临时素材的 ID，可通过 UploadMedia 上传得到
+}
+
+// QYMsgImage 企业图片消息
+type QYMsgImage struct {
+	*QYMsg
+	Image QYMsgItemMedia `json:"image"`
+}
+
+// QYMsgVoice 企业语音消息。仅支持 AMR 格式
+type QYMsgVoice struct {
+	*QYMsg
+	Voice QYMsgItemMedia `json:"voice"`
+}
+
+// QYMsgFile 企业文件消息
+type QYMsgFile struct {
+	*QYMsg
+	File QYMsgItemMedia `json:"file"`
+}
+
+// QYMsgItemVideo 企业视频消息
+type QYMsgItemVideo struct {
+	MediaID     string `json:"media_id"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// QYMsgVideo 企业视频消息
+type QYMsgVideo struct {
+	*QYMsg
+	Video QYMsgItemVideo `json:"video"`
+}
+
+// QYMsgItemArticle 企业图文消息中的一篇文章
+type QYMsgItemArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Url         string `json:"url,omitempty"`    // 点击后跳转的链接。与小程序二选一
+	Picurl      string `json:"picurl,omitempty"` // 图片链接
+	Appid       string `json:"appid,omitempty"`  // 点击后跳转的小程序的 appid
+	Pagepath    string `json:"pagepath,omitempty"`
+}
+
+// QYMsgItemNews 企业图文消息。最多 8 篇文章
+type QYMsgItemNews struct {
+	Articles []QYMsgItemArticle `json:"articles"`
+}
+
+// QYMsgNews 企业图文消息
+type QYMsgNews struct {
+	*QYMsg
+	News QYMsgItemNews `json:"news"`
+}
+
+// QYMsgItemMpArticle 企业图文消息（mpnews）中的一篇文章，内容存储在企业微信中
+type QYMsgItemMpArticle struct {
+	Title            string `json:"title"`
+	ThumbMediaID     string `json:"thumb_media_id"` // 封面图片的临时素材 ID
+	Author           string `json:"author,omitempty"`
+	ContentSourceUrl string `json:"content_source_url,omitempty"` // 点击“阅读原文”后打开的链接
+	Content          string `json:"content"`                      // 支持 HTML 标签
+	Digest           string `json:"digest,omitempty"`
+}
+
+// QYMsgItemMpnews 企业图文消息（mpnews）。最多 8 篇文章
+type QYMsgItemMpnews struct {
+	Articles []QYMsgItemMpArticle `json:"articles"`
+}
+
+// QYMsgMpnews 企业图文消息（mpnews）
+type QYMsgMpnews struct {
+	*QYMsg
+	Mpnews QYMsgItemMpnews `json:"mpnews"`
+}
+
+// 企业模板卡片的类型
+const (
+	CardTextNotice          = "text_notice"
+	CardNewsNotice          = "news_notice"
+	CardButtonInteraction   = "button_interaction"
+	CardVoteInteraction     = "vote_interaction"
+	CardMultipleInteraction = "multiple_interaction"
+)
+
+// QYCardSource 模板卡片的来源
+type QYCardSource struct {
+	IconUrl   string `json:"icon_url,omitempty"`
+	Desc      string `json:"desc,omitempty"`
+	DescColor int    `json:"desc_color,omitempty"` // 0 灰色，1 黑色，2 红色，3 绿色
+}
+
+// QYCardTitle 模板卡片的主标题、关键数据
+type QYCardTitle struct {
+	Title string `json:"title,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// QYCardHorizontalContent 模板卡片的二级标题+文本
+type QYCardHorizontalContent struct {
+	Type    int    `json:"type,omitempty"` // 0 普通文本，1 跳转链接，2 下载附件，3 成员详情
+	Keyname string `json:"keyname"`
+	Value   string `json:"value,omitempty"`
+	Url     string `json:"url,omitempty"`
+	MediaID string `json:"media_id,omitempty"`
+	Userid  string `json:"userid,omitempty"`
+}
+
+// QYCardJump 模板卡片的跳转指引
+type QYCardJump struct {
+	Type     int    `json:"type,omitempty"` // 0 无跳转，1 跳转链接，2 跳转小程序
+	Title    string `json:"title"`
+	Url      string `json:"url,omitempty"`
+	Appid    string `json:"appid,omitempty"`
+	Pagepath string `json:"pagepath,omitempty"`
+}
+
+// QYCardAction 模板卡片整体的点击跳转
+type QYCardAction struct {
+	Type     int    `json:"type"` // 0 无跳转，1 跳转链接，2 跳转小程序
+	Url      string `json:"url,omitempty"`
+	Appid    string `json:"appid,omitempty"`
+	Pagepath string `json:"pagepath,omitempty"`
+}
+
+// QYCardImage 模板卡片的图片
+type QYCardImage struct {
+	Url         string  `json:"url"`
+	AspectRatio float64 `json:"aspect_ratio,omitempty"`
+}
+
+// QYCardButton 模板卡片的按钮
+type QYCardButton struct {
+	Text  string `json:"text"`
+	Style int    `json:"style,omitempty"` // 1~4
+	Key   string `json:"key"`             // 回调事件中携带的值
+}
+
+// QYCardOption 模板卡片的选项
+type QYCardOption struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	IsChecked bool   `json:"is_checked,omitempty"`
+}
+
+// QYCardCheckbox 投票卡片的选择题
+type QYCardCheckbox struct {
+	QuestionKey string         `json:"question_key"`
+	OptionList  []QYCardOption `json:"option_list"`
+	Mode        int            `json:"mode,omitempty"` // 0 单选，1 多选
+}
+
+// QYCardSelect 多项选择卡片的下拉框
+type QYCardSelect struct {
+	QuestionKey string         `json:"question_key"`
+	Title       string         `json:"title,omitempty"`
+	SelectedID  string         `json:"selected_id,omitempty"`
+	OptionList  []QYCardOption `json:"option_list"`
+}
+
+// QYCardSubmitButton 投票、多项选择卡片的提交按钮
+type QYCardSubmitButton struct {
+	Text string `json:"text"`
+	Key  string `json:"key"`
+}
+
+// QYMsgItemTemplateCard 企业模板卡片。不同 CardType 可用的字段不同
+//
+// @see https://developer.work.weixin.qq.com/document/path/90236#%E6%A8%A1%E6%9D%BF%E5%8D%A1%E7%89%87%E6%B6%88%E6%81%AF
+type QYMsgItemTemplateCard struct {
+	CardType              string                    `json:"card_type"` // 如 CardTextNotice
+	Source                *QYCardSource             `json:"source,omitempty"`
+	MainTitle             *QYCardTitle              `json:"main_title,omitempty"`
+	EmphasisContent       *QYCardTitle              `json:"emphasis_content,omitempty"`
+	SubTitleText          string                    `json:"sub_title_text,omitempty"`
+	CardImage             *QYCardImage              `json:"card_image,omitempty"`
+	HorizontalContentList []QYCardHorizontalContent `json:"horizontal_content_list,omitempty"`
+	JumpList              []QYCardJump              `json:"jump_list,omitempty"`
+	CardAction            *QYCardAction             `json:"card_action,omitempty"`
+	TaskID                string                    `json:"task_id,omitempty"` // 交互类卡片必填，用于更新卡片
+	ButtonList            []QYCardButton            `json:"button_list,omitempty"`
+	Checkbox              *QYCardCheckbox           `json:"checkbox,omitempty"`
+	SelectList            []QYCardSelect            `json:"select_list,omitempty"`
+	SubmitButton          *QYCardSubmitButton       `json:"submit_button,omitempty"`
+}
+
+// QYMsgTemplateCard 企业模板卡片消息
+type QYMsgTemplateCard struct {
+	*QYMsg
+	TemplateCard QYMsgItemTemplateCard `json:"template_card"`
+}
+
+// QYTaskcardButton 任务卡片的按钮
+type QYTaskcardButton struct {
+	Key         string `json:"key"` // 回调事件中携带的值
+	Name        string `json:"name"`
+	ReplaceName string `json:"replace_name,omitempty"` // 点击后按钮显示的文本
+	Color       string `json:"color,omitempty"`        // "red"、"blue"
+	IsBold      bool   `json:"is_bold,omitempty"`
+}
+
+// QYMsgItemTaskcard 企业任务卡片（交互式）消息
+type QYMsgItemTaskcard struct {
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Url         string             `json:"url,omitempty"`
+	TaskID      string             `json:"task_id"` // 同一应用内唯一
+	Btn         []QYTaskcardButton `json:"btn"`
+}
+
+// QYMsgInteractive 企业任务卡片（交互式）消息
+type QYMsgInteractive struct {
+	*QYMsg
+	InteractiveTaskcard QYMsgItemTaskcard `json:"interactive_taskcard"`
+}
+
+// 企业微信群机器人消息推送
+
+// GBMsgItemText 群机器人文本消息
+type GBMsgItemText struct {
+	Content             string   `json:"content"`
+	MentionedList       []string `json:"mentioned_list,omitempty"`        // 提醒的成员的 userid
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"` // 提醒的成员的手机号
+}
+
+// GBMsgText 群机器人文本消息
+type GBMsgText struct {
+	Msgtype string        `json:"msgtype"`
+	Text    GBMsgItemText `json:"text"`
+}
+
+// GBMsgMarkdown 群机器人 Markdown 消息
+type GBMsgMarkdown struct {
+	Msgtype  string        `json:"msgtype"`
+	Markdown QYMsgItemText `json:"markdown"`
+}
+
+// GBMsgItemImage 群机器人图片消息
+type GBMsgItemImage struct {
+	Base64 string `json:"base64"` // 图片内容的 base64 编码
+	Md5    string `json:"md5"`    // 图片内容（编码前）的 MD5 值
+}
+
+// GBMsgImage 群机器人图片消息
+type GBMsgImage struct {
+	Msgtype string         `json:"msgtype"`
+	Image   GBMsgItemImage `json:"image"`
+}
+
+// GBMsgNews 群机器人图文消息
+type GBMsgNews struct {
+	Msgtype string        `json:"msgtype"`
+	News    QYMsgItemNews `json:"news"`
+}
+
+// GBMsgFile 群机器人文件消息
+type GBMsgFile struct {
+	Msgtype string         `json:"msgtype"`
+	File    QYMsgItemMedia `json:"file"`
+}
+
+// 企业微信回调消息
+
+// CallbackEvent 事件消息的类型
+const CallbackEvent = "event"
+
+// 常用的事件类型
+const (
+	EventSubscribe    = "subscribe"
+	EventEnterAgent   = "enter_agent"
+	EventClick        = "click"
+	EventView         = "view"
+	EventTaskcard     = "taskcard_click"
+	EventTemplateCard = "template_card_event"
+)
+
+// CallbackMsg 回调的消息、事件。不同类型可用的字段不同
+type CallbackMsg struct {
+	ToUserName   string `xml:"ToUserName"`   // 企业的 corpid
+	FromUserName string `xml:"FromUserName"` // 成员的 userid
+	CreateTime   int64  `xml:"CreateTime"`
+	MsgType      string `xml:"MsgType"` // 如"text"、"image"、CallbackEvent
+	AgentID      int    `xml:"AgentID"`
+	MsgId        int64  `xml:"MsgId"`
+
+	// 文本消息
+	Content string `xml:"Content"`
+	// 图片、语音、视频消息
+	PicUrl  string `xml:"PicUrl"`
+	MediaId string `xml:"MediaId"`
+	Format  string `xml:"Format"`
+
+	// 事件
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+	// 任务卡片、模板卡片事件
+	TaskId       string `xml:"TaskId"`
+	CardType     string `xml:"CardType"`
+	ResponseCode string `xml:"ResponseCode"` // 用于更新模板卡片
+}
+
+// Reply 被动回复的消息
+type Reply struct {
+	// 消息类型，可选 QYTypeText、QYTypeImage、QYTypeVoice
+	MsgType string
+	// 文本消息的内容
+	Content string
+	// 图片、语音消息的临时素材 ID
+	MediaID string
+}
+
+// 以 CDATA 序列化的文本
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// 被动回复的 XML
+type replyXML struct {
+	XMLName      xml.Name    `xml:"xml"`
+	ToUserName   cdata       `xml:"ToUserName"`
+	FromUserName cdata       `xml:"FromUserName"`
+	CreateTime   int64       `xml:"CreateTime"`
+	MsgType      cdata       `xml:"MsgType"`
+	Content      *cdata      `xml:"Content,omitempty"`
+	Image        *replyMedia `xml:"Image,omitempty"`
+	Voice        *replyMedia `xml:"Voice,omitempty"`
+}
+
+// 被动回复的媒体
+type replyMedia struct {
+	MediaID cdata `xml:"MediaId"`
+}
+
+// 微信公众号消息推送
+
+// TplData 模板消息的字段，键为模板中的字段名（如"keyword1"）
+type TplData map[string]SBMsgItem
+
+// TplMiniprogram 点击模板消息后跳转的小程序
+type TplMiniprogram struct {
+	Appid    string `json:"appid"`
+	Pagepath string `json:"pagepath,omitempty"`
+}
+
+// TplMsg 公众号模板消息
+type TplMsg struct {
+	Touser      string          `json:"touser"` // 用户的 openid
+	TemplateID  string          `json:"template_id"`
+	Url         string          `json:"url,omitempty"` // 点击后跳转的链接
+	Miniprogram *TplMiniprogram `json:"miniprogram,omitempty"`
+	// 模板的字段。可为 TplData，或者字段均为 SBMsgItem 的结构体（如 SBMsg）
+	Data        interface{} `json:"data"`
+	ClientMsgID string      `json:"client_msg_id,omitempty"` // 防重入 ID
+}
+
+// Template 公众号已添加的模板
+type Template struct {
+	TemplateID      string `json:"template_id"`
+	Title           string `json:"title"`
+	PrimaryIndustry string `json:"primary_industry"`
+	DeputyIndustry  string `json:"deputy_industry"`
+	Content         string `json:"content"` // 模板内容，如"{{first.DATA}}"
+	Example         string `json:"example"`
+}
+
+// CustomMsg 公众号客服消息。Text、Image、News 按 Msgtype 选其一
+type CustomMsg struct {
+	Touser  string          `json:"touser"`
+	Msgtype string          `json:"msgtype"`
+	Text    *QYMsgItemText  `json:"text,omitempty"`
+	Image   *QYMsgItemMedia `json:"image,omitempty"`
+	News    *QYMsgItemNews  `json:"news,omitempty"`
+}
+
+// SubscribeValue 订阅通知的字段值
+type SubscribeValue struct {
+	Value string `json:"value"`
+}
+
+// SubscribeMsg 公众号订阅通知
+type SubscribeMsg struct {
+	Touser      string                    `json:"touser"`
+	TemplateID  string                    `json:"template_id"`
+	Page        string                    `json:"page,omitempty"` // 点击后跳转的链接
+	Miniprogram *TplMiniprogram           `json:"miniprogram,omitempty"`
+	Data        map[string]SubscribeValue `json:"data"` // 键为模板中的字段名，如"thing1"
+}
+
+// UserList 公众号的用户 openid 列表
+type UserList struct {
+	Total int `json:"total"` // 关注者总数
+	Count int `json:"count"` // 本次获取的数量
+	Data  struct {
+		Openid []string `json:"openid"`
+	} `json:"data"`
+	NextOpenid string `json:"next_openid"`
+}
+
+// UserInfo 公众号用户的基本信息
+type UserInfo struct {
+	Subscribe      int    `json:"subscribe"` // 为 0 时未关注，其它字段为空
+	Openid         string `json:"openid"`
+	Language       string `json:"language"`
+	SubscribeTime  int64  `json:"subscribe_time"`
+	Unionid        string `json:"unionid"`
+	Remark         string `json:"remark"`
+	Groupid        int    `json:"groupid"`
+	TagidList      []int  `json:"tagid_list"`
+	SubscribeScene string `json:"subscribe_scene"`
+}
+
+// UserTag 公众号的用户标签
+type UserTag struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"` // 标签下的用户数
+}
+
+// 企业微信通讯录
+
+// Department 企业的部门
+type Department struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Parentid int    `json:"parentid"` // 父部门 ID，根部门为 0
+	Order    int    `json:"order"`
+}
+
+// QYUser 企业的成员
+type QYUser struct {
+	Userid     string `json:"userid"`
+	Name       string `json:"name"`
+	Department []int  `json:"department"`
+}
+
+// QYTag 企业的标签
+type QYTag struct {
+	Tagid   int    `json:"tagid"`
+	Tagname string `json:"tagname"`
+}
+
+// CardButtonUpdate 更新后的模板卡片按钮
+type CardButtonUpdate struct {
+	ReplaceName string `json:"replace_name"` // 按钮替换后的文本
+}
+
+// TemplateCardUpdate 更新已推送的模板卡片。Button 与 TemplateCard 二选一
+type TemplateCardUpdate struct {
+	Userids      []string `json:"userids,omitempty"`
+	Partyids     []int    `json:"partyids,omitempty"`
+	Tagids       []int    `json:"tagids,omitempty"`
+	Atall        int      `json:"atall,omitempty"` // 为 1 时更新所有接收者的卡片
+	Agentid      int      `json:"agentid"`
+	ResponseCode string   `json:"response_code"` // 推送卡片或回调事件中的 ResponseCode，72 小时内有效、只能使用一次
+	// 只将按钮更新为不可点击的文本
+	Button *CardButtonUpdate `json:"button,omitempty"`
+	// 更新为新的卡片
+	TemplateCard *QYMsgItemTemplateCard `json:"template_card,omitempty"`
+}
@@ -0,0 +1,232 @@
+package dowx
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// 发送消息
+	gbSendURL = "%s/cgi-bin/webhook/send?key=%s"
+	// 上传文件
+	gbUploadURL = "%s/cgi-bin/webhook/upload_media?key=%s&type=file"
+
+	// GroupBotLimit 群机器人每分钟最多发送的消息数
+	GroupBotLimit = 20
+	// 超过发送频率限制时的错误码
+	gbErrcodeLimit = 45009
+	// 图片消息的图片大小限制
+	gbMaxImageSize = 2 * 1024 * 1024
+)
+
+var (
+	// ErrGroupBotLimit 超过群机器人的发送频率限制
+	ErrGroupBotLimit = errors.New("超过群机器人的发送频率限制")
+)
+
+// GroupBot 企业微信群机器人，通过 webhook 推送消息到群聊
+//
+// 与应用消息不同，无需 corpid、agentid，只需 webhook 中的 key
+//
+// 每个机器人每分钟最多发送 GroupBotLimit 条消息，超出时将等待后发送。可在多个协程中使用
+//
+// @see https://developer.work.weixin.qq.com/document/path/91770
+type GroupBot struct {
+	key  string
+	addr string
+
+	mu sync.Mutex
+	// 最近已发送的时刻
+	sent []time.Time
+	// 限制频率的时间窗口
+	period time.Duration
+}
+
+// NewGroupBot 创建群机器人
+//
+// key webhook 地址中"key="后的部分
+func NewGroupBot(key string) *GroupBot {
+	return &GroupBot{key: key, addr: QYAddr, period: time.Minute}
+}
+
+// Push 推送消息。可用 GBMsgText、GBMsgMarkdown、GBMsgImage、GBMsgNews、GBMsgFile
+//
+// 被服务端限制频率时（如多个程序使用同一个机器人），将在等待后重发一次
+func (g *GroupBot) Push(data interface{}) error {
+	for i := 0; ; i++ {
+		g.wait()
+
+		err := g.send(data)
+		if errors.Is(err, ErrGroupBotLimit) && i == 0 {
+			time.Sleep(g.period)
+			continue
+		}
+		return err
+	}
+}
+
+// SetAddr 设置接口地址，如测试用的模拟服务的地址
+//
+// addr 如 "http://127.0.0.1:12345"，末尾不含"/"
+func (g *GroupBot) SetAddr(addr string) {
+	g.addr = addr
+}
+
+// 发送一次消息
+func (g *GroupBot) send(data interface{}) error {
+	bs, err := client.PostJSONObj(fmt.Sprintf(gbSendURL, g.addr, g.key), data, nil)
+	if err != nil {
+		return fmt.Errorf("推送消息时网络出错：%w", err)
+	}
+
+	var result PushResult
+	err = json.Unmarshal(bs, &result)
+	if err != nil {
+		return fmt.Errorf("解析推送响应 JSON 文本时出错：%w", err)
+	}
+	if result.Errcode == gbErrcodeLimit {
+		return fmt.Errorf("推送时出错：%w：%s", ErrGroupBotLimit, string(bs))
+	}
+	if result.Errcode != 0 {
+		return fmt.Errorf("推送时出错：%s", string(bs))
+	}
+
+	return nil
+}
+
+// 等待到可发送的时刻，并记录发送
+func (g *GroupBot) wait() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for {
+		now := time.Now()
+		// 移除时间窗口之外的记录
+		kept := g.sent[:0]
+		for _, t := range g.sent {
+			if now.Sub(t) < g.period {
+				kept = append(kept, t)
+			}
+		}
+		g.sent = kept
+
+		if len(g.sent) < GroupBotLimit {
+			g.sent = append(g.sent, now)
+			return
+		}
+
+		// 等到最早的记录移出时间窗口
+		time.Sleep(g.period - now.Sub(g.sent[0]))
+	}
+}
+
+// PushText 推送文本消息
+//
+// mentioned 需要提醒的成员的 userid，"@all"表示提醒所有人，可为 nil
+//
+// mobiles 需要提醒的成员的手机号，"@all"表示提醒所有人，可为 nil
+func (g *GroupBot) PushText(content string, mentioned []string, mobiles []string) error {
+	data := GBMsgText{
+		Msgtype: QYTypeText,
+		Text: GBMsgItemText{
+			Content:             content,
+			MentionedList:       mentioned,
+			MentionedMobileList: mobiles,
+		},
+	}
+
+	return g.Push(data)
+}
+
+// PushMarkdown 推送 Markdown 消息。可用"<@userid>"提醒成员
+func (g *GroupBot) PushMarkdown(content string) error {
+	data := GBMsgMarkdown{
+		Msgtype:  QYTypeMarkdown,
+		Markdown: QYMsgItemText{Content: content},
+	}
+
+	return g.Push(data)
+}
+
+// PushImage 推送图片消息
+//
+// path 图片的路径。支持 JPG、PNG 格式，大小不超过 2MB
+func (g *GroupBot) PushImage(path string) error {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取图片'%s'出错：%w", path, err)
+	}
+	if len(bs) > gbMaxImageSize {
+		return fmt.Errorf("图片'%s'超过了 2MB", path)
+	}
+
+	sum := md5.Sum(bs)
+	data := GBMsgImage{
+		Msgtype: QYTypeImage,
+		Image: GBMsgItemImage{
+			Base64: base64.StdEncoding.EncodeToString(bs),
+			Md5:    hex.EncodeToString(sum[:]),
+		},
+	}
+
+	return g.Push(data)
+}
+
+// PushNews 推送图文消息
+//
+// articles 1 到 8 篇文章，只会用到标题、描述、链接、图片链接
+func (g *GroupBot) PushNews(articles []QYMsgItemArticle) error {
+	data := GBMsgNews{
+		Msgtype: QYTypeNews,
+		News:    QYMsgItemNews{Articles: articles},
+	}
+
+	return g.Push(data)
+}
+
+// UploadMedia 上传文件，返回 3 天内有效的 media_id，用于发送文件消息
+//
+// 文件大小在 5B 到 20MB 之间
+func (g *GroupBot) UploadMedia(path string) (string, error) {
+	bs, err := client.PostFiles(fmt.Sprintf(gbUploadURL, g.addr, g.key),
+		map[string]interface{}{"media": path}, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("上传文件'%s'时网络出错：%w", path, err)
+	}
+
+	var result struct {
+		PushResult
+		MediaResult
+	}
+	err = json.Unmarshal(bs, &result)
+	if err != nil {
+		return "", fmt.Errorf("解析上传响应 JSON 文本时出错：%w", err)
+	}
+	if result.Errcode != 0 {
+		return "", fmt.Errorf("上传文件'%s'时出错：%s", path, string(bs))
+	}
+
+	return result.MediaID, nil
+}
+
+// PushFile 上传文件，并作为文件消息推送
+func (g *GroupBot) PushFile(path string) error {
+	mediaID, err := g.UploadMedia(path)
+	if err != nil {
+		return err
+	}
+
+	data := GBMsgFile{
+		Msgtype: QYTypeFile,
+		File:    QYMsgItemMedia{MediaID: mediaID},
+	}
+
+	return g.Push(data)
+}
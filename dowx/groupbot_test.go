@@ -0,0 +1,58 @@
+package dowx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupBot_PushText(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		count int
+		last  GBMsgText
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Query().Get("key") != "key" {
+			t.Errorf("key 不符：%s", r.URL)
+		}
+		count++
+		json.NewDecoder(r.Body).Decode(&last)
+		// 第一次请求返回频率限制
+		if count == 1 {
+			w.Write([]byte(`{"errcode":45009,"errmsg":"api freq out of limit"}`))
+			return
+		}
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	bot := NewGroupBot("key")
+	bot.SetAddr(srv.URL)
+	bot.period = 50 * time.Millisecond
+
+	err := bot.PushText("测试", []string{"@all"}, []string{"13800000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || last.Text.MentionedList[0] != "@all" || last.Text.MentionedMobileList[0] != "13800000000" {
+		t.Errorf("请求不符：%d 次，%+v", count, last)
+	}
+
+	// 超过限额后需等待
+	start := time.Now()
+	for i := 0; i < GroupBotLimit; i++ {
+		if err = bot.PushMarkdown("测试"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Since(start) < bot.period/2 {
+		t.Errorf("超过限额后未等待")
+	}
+}
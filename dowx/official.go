@@ -0,0 +1,236 @@
+package dowx
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	// 获取 Token
+	mpTokenURL = "/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s"
+
+	// 模板消息
+	mpTplSendURL = "/cgi-bin/message/template/send?access_token=%s"
+	mpTplListURL = "/cgi-bin/template/get_all_private_template?access_token=%s"
+	mpTplAddURL  = "/cgi-bin/template/api_add_template?access_token=%s"
+	mpTplDelURL  = "/cgi-bin/template/del_private_template?access_token=%s"
+
+	// 客服消息
+	mpCustomSendURL = "/cgi-bin/message/custom/send?access_token=%s"
+	// 订阅通知
+	mpSubscribeSendURL = "/cgi-bin/message/subscribe/bizsend?access_token=%s"
+
+	// 用户
+	mpUserListURL = "/cgi-bin/user/get?access_token=%s"
+	mpUserInfoURL = "/cgi-bin/user/info?access_token=%s"
+	// 标签
+	mpTagListURL    = "/cgi-bin/tags/get?access_token=%s"
+	mpTagUsersURL   = "/cgi-bin/user/tag/get?access_token=%s"
+	mpUserTagsURL   = "/cgi-bin/tags/getidlist?access_token=%s"
+	mpBatchTagURL   = "/cgi-bin/tags/members/batchtagging?access_token=%s"
+	mpBatchUntagURL = "/cgi-bin/tags/members/batchuntagging?access_token=%s"
+)
+
+// Official 微信公众号（服务号）的消息推送、用户管理对象
+//
+// 与 Sandbox 不同，模板消息的字段可任意指定，并支持客服消息、订阅通知等
+type Official struct {
+	*Core
+}
+
+// NewOfficial 获取 Official 实例
+func NewOfficial(appid string, secret string) *Official {
+	return &Official{Core: newCore(MPAddr, appid, secret)}
+}
+
+// SendTemplate 推送模板消息
+//
+// 模板的字段在 TplMsg.Data 中指定，如 TplData{"first": {Value: "标题"}, "keyword1": {Value: "内容"}}
+func (o *Official) SendTemplate(msg *TplMsg) (*PushResult, error) {
+	return o.Core.push(mpTokenURL, mpTplSendURL, msg)
+}
+
+// Templates 获取已添加的模板列表
+func (o *Official) Templates() ([]Template, error) {
+	var result struct {
+		TemplateList []Template `json:"template_list"`
+	}
+	err := o.Core.call(mpTokenURL, mpTplListURL, nil, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取模板列表出错：%w", err)
+	}
+
+	return result.TemplateList, nil
+}
+
+// AddTemplate 从模板库添加模板，返回模板 ID
+//
+// shortID 模板库中模板的编号，如"TM00015"
+//
+// keywords 选用的关键词名称。类目模板需指定
+func (o *Official) AddTemplate(shortID string, keywords []string) (string, error) {
+	data := map[string]interface{}{"template_id_short": shortID, "keyword_name_list": keywords}
+
+	var result struct {
+		TemplateID string `json:"template_id"`
+	}
+	err := o.Core.call(mpTokenURL, mpTplAddURL, nil, data, &result)
+	if err != nil {
+		return "", fmt.Errorf("添加模板'%s'出错：%w", shortID, err)
+	}
+
+	return result.TemplateID, nil
+}
+
+// DelTemplate 删除模板
+func (o *Official) DelTemplate(templateID string) error {
+	err := o.Core.call(mpTokenURL, mpTplDelURL, nil, map[string]string{"template_id": templateID}, nil)
+	if err != nil {
+		return fmt.Errorf("删除模板'%s'出错：%w", templateID, err)
+	}
+
+	return nil
+}
+
+// SendCustom 推送客服消息。只能在用户 48 小时内与公众号有过互动（如发送消息、点击菜单）时推送
+func (o *Official) SendCustom(msg *CustomMsg) error {
+	_, err := o.Core.push(mpTokenURL, mpCustomSendURL, msg)
+	return err
+}
+
+// SendCustomText 推送文本客服消息
+func (o *Official) SendCustomText(openid string, content string) error {
+	return o.SendCustom(&CustomMsg{Touser: openid, Msgtype: QYTypeText, Text: &QYMsgItemText{Content: content}})
+}
+
+// SendCustomImage 推送图片客服消息
+//
+// mediaID 图片的临时素材 ID
+func (o *Official) SendCustomImage(openid string, mediaID string) error {
+	return o.SendCustom(&CustomMsg{Touser: openid, Msgtype: QYTypeImage, Image: &QYMsgItemMedia{MediaID: mediaID}})
+}
+
+// SendCustomNews 推送图文客服消息。目前只支持 1 篇文章，只会用到标题、描述、链接、图片链接
+func (o *Official) SendCustomNews(openid string, article QYMsgItemArticle) error {
+	return o.SendCustom(&CustomMsg{Touser: openid, Msgtype: QYTypeNews,
+		News: &QYMsgItemNews{Articles: []QYMsgItemArticle{article}}})
+}
+
+// SendSubscribe 推送订阅通知。用户需已订阅该模板
+func (o *Official) SendSubscribe(msg *SubscribeMsg) error {
+	_, err := o.Core.push(mpTokenURL, mpSubscribeSendURL, msg)
+	return err
+}
+
+// Users 获取关注者的 openid 列表。一次最多 10000 个
+//
+// nextOpenid 从该 openid 之后开始获取，为空""时从头开始
+func (o *Official) Users(nextOpenid string) (*UserList, error) {
+	query := url.Values{}
+	if nextOpenid != "" {
+		query.Set("next_openid", nextOpenid)
+	}
+
+	var result UserList
+	err := o.Core.call(mpTokenURL, mpUserListURL, query, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取关注者列表出错：%w", err)
+	}
+
+	return &result, nil
+}
+
+// AllUsers 获取所有关注者的 openid
+func (o *Official) AllUsers() ([]string, error) {
+	openids := make([]string, 0)
+	next := ""
+	for {
+		list, err := o.Users(next)
+		if err != nil {
+			return nil, err
+		}
+		openids = append(openids, list.Data.Openid...)
+
+		// 已获取完
+		if list.Count == 0 || list.NextOpenid == "" || len(openids) >= list.Total {
+			return openids, nil
+		}
+		next = list.NextOpenid
+	}
+}
+
+// UserInfo 获取用户的基本信息
+func (o *Official) UserInfo(openid string) (*UserInfo, error) {
+	query := url.Values{"openid": {openid}, "lang": {"zh_CN"}}
+
+	var result UserInfo
+	err := o.Core.call(mpTokenURL, mpUserInfoURL, query, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户'%s'的信息出错：%w", openid, err)
+	}
+
+	return &result, nil
+}
+
+// Tags 获取已创建的标签
+func (o *Official) Tags() ([]UserTag, error) {
+	var result struct {
+		Tags []UserTag `json:"tags"`
+	}
+	err := o.Core.call(mpTokenURL, mpTagListURL, nil, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取标签列表出错：%w", err)
+	}
+
+	return result.Tags, nil
+}
+
+// TagUsers 获取标签下的用户。一次最多 10000 个
+//
+// nextOpenid 从该 openid 之后开始获取，为空""时从头开始
+func (o *Official) TagUsers(tagID int, nextOpenid string) (*UserList, error) {
+	data := map[string]interface{}{"tagid": tagID, "next_openid": nextOpenid}
+
+	var result UserList
+	err := o.Core.call(mpTokenURL, mpTagUsersURL, nil, data, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取标签'%d'下的用户出错：%w", tagID, err)
+	}
+
+	return &result, nil
+}
+
+// UserTags 获取用户的标签 ID
+func (o *Official) UserTags(openid string) ([]int, error) {
+	var result struct {
+		TagidList []int `json:"tagid_list"`
+	}
+	err := o.Core.call(mpTokenURL, mpUserTagsURL, nil, map[string]string{"openid": openid}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户'%s'的标签出错：%w", openid, err)
+	}
+
+	return result.TagidList, nil
+}
+
+// TagUsersAdd 为用户打标签。一次最多 50 个用户
+func (o *Official) TagUsersAdd(tagID int, openids []string) error {
+	data := map[string]interface{}{"tagid": tagID, "openid_list": openids}
+	err := o.Core.call(mpTokenURL, mpBatchTagURL, nil, data, nil)
+	if err != nil {
+		return fmt.Errorf("为用户打标签'%d'出错：%w", tagID, err)
+	}
+
+	return nil
+}
+
+// TagUsersRemove 为用户取消标签。一次最多 50 个用户
+func (o *Official) TagUsersRemove(tagID int, openids []string) error {
+	data := map[string]interface{}{"tagid": tagID, "openid_list": openids}
+	err := o.Core.call(mpTokenURL, mpBatchUntagURL, nil, data, nil)
+	if err != nil {
+		return fmt.Errorf("为用户取消标签'%d'出错：%w", tagID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package dowx
+
+import (
+	"github.com/donething/utils-go/dowx/dowxtest"
+	"strings"
+	"testing"
+)
+
+func TestOfficial_SendTemplate(t *testing.T) {
+	srv := dowxtest.NewServer()
+	defer srv.Close()
+
+	official := NewOfficial("appid", "secret")
+	official.SetAddr(srv.URL)
+
+	_, err := official.SendTemplate(&TplMsg{
+		Touser:     "user",
+		TemplateID: "tpl",
+		Data: TplData{
+			"first":    {Value: "测试标题"},
+			"keyword1": {Value: "测试消息内容", Color: "#173177"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg struct {
+		Data TplData `json:"data"`
+	}
+	if err = srv.Messages()[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Data["keyword1"].Color != "#173177" {
+		t.Errorf("推送的消息不符：%+v", msg)
+	}
+}
+
+func TestOfficial_AllUsers(t *testing.T) {
+	srv := dowxtest.NewServer()
+	defer srv.Close()
+	srv.AddFollowers("o1", "o2", "o3", "o4", "o5")
+	// 每页 2 个，需翻页获取
+	srv.SetPageSize(2)
+
+	official := NewOfficial("appid", "secret")
+	official.SetAddr(srv.URL)
+
+	openids, err := official.AllUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(openids, ",") != "o1,o2,o3,o4,o5" {
+		t.Errorf("获取的关注者不符：%v", openids)
+	}
+}
@@ -7,10 +7,17 @@ import (
 )
 
 const (
+	// QYAddr 企业微信接口的默认地址
+	QYAddr = "https://qyapi.weixin.qq.com"
+
 	// 获取 Token
-	qyTokenURL = "https://qyapi.weixin.qq.com/cgi-bin/gettoken?corpid=%s&corpsecret=%s"
+	qyTokenURL = "/cgi-bin/gettoken?corpid=%s&corpsecret=%s"
 	// 发送消息
-	qySendURL = "https://qyapi.weixin.qq.com/cgi-bin/message/send?access_token=%s"
+	qySendURL = "/cgi-bin/message/send?access_token=%s"
+	// 撤回消息
+	qyRecallURL = "/cgi-bin/message/recall?access_token=%s"
+	// 更新模板卡片
+	qyUpdateCardURL = "/cgi-bin/message/update_template_card?access_token=%s"
 )
 
 // QiYe 企业微信消息推送对象
@@ -20,12 +27,78 @@ type QiYe struct {
 
 // NewQiYe 获取 QiYe 实例，以推送消息
 func NewQiYe(corpid string, corpsecret string) *QiYe {
-	return &QiYe{&Core{appid: corpid, secret: corpsecret, token: "", expires: time.Now()}}
+	return &QiYe{newCore(QYAddr, corpid, corpsecret)}
 }
 
 // Push 推送消息
-func (q *QiYe) Push(data interface{}) error {
-	return q.Core.push(qyTokenURL, qySendURL, data)
+//
+// 部分接收者无效时，消息仍会推送给其它接收者，并返回 *PartialError
+func (q *QiYe) Push(data interface{}) (*PushResult, error) {
+	return q.send(data)
+}
+
+// PushTo 推送消息到 to 指定的成员、部门、标签。将覆盖消息中已设置的接收者
+//
+// msg 需包含公共部分 *QYMsg（如 &QYMsgText{QYMsg: &QYMsg{Agentid: 1, Msgtype: QYTypeText}}），否则返回错误
+//
+// 部分接收者无效时，消息仍会推送给其它接收者，并返回 *PartialError
+func (q *QiYe) PushTo(to *Recipient, msg QYMessage) (*PushResult, error) {
+	if to == nil {
+		return nil, fmt.Errorf("没有指定接收者")
+	}
+	if msg == nil || msg.Base() == nil {
+		return nil, fmt.Errorf("消息缺少公共部分 *QYMsg")
+	}
+
+	to.apply(msg.Base())
+	return q.send(msg)
+}
+
+// 推送消息，并检查无效的接收者
+func (q *QiYe) send(data interface{}) (*PushResult, error) {
+	result, err := q.Core.push(qyTokenURL, qySendURL, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, partialError(result)
+}
+
+// Recall 撤回 24 小时内推送的消息
+//
+// msgid 推送时返回的 PushResult.Msgid
+func (q *QiYe) Recall(msgid MsgID) error {
+	err := q.Core.call(qyTokenURL, qyRecallURL, nil, map[string]MsgID{"msgid": msgid}, nil)
+	if err != nil {
+		return fmt.Errorf("撤回消息'%s'出错：%w", msgid, err)
+	}
+
+	return nil
+}
+
+// UpdateTemplateCard 更新已推送的模板卡片，如在任务状态变化后更新其内容
+//
+// 部分接收者无效时，其它接收者的卡片仍会更新，并返回 *PartialError
+func (q *QiYe) UpdateTemplateCard(update *TemplateCardUpdate) error {
+	var result PushResult
+	err := q.Core.call(qyTokenURL, qyUpdateCardURL, nil, update, &result)
+	if err != nil {
+		return fmt.Errorf("更新模板卡片出错：%w", err)
+	}
+
+	return partialError(&result)
+}
+
+// UpdateCardButton 将所有接收者的模板卡片的按钮更新为不可点击的文本，如"已处理"
+//
+// responseCode 推送卡片时返回的 PushResult.ResponseCode，或者回调事件中的 CallbackMsg.ResponseCode
+func (q *QiYe) UpdateCardButton(agentid int, responseCode string, replaceName string) error {
+	return q.UpdateTemplateCard(&TemplateCardUpdate{
+		Atall:        1,
+		Agentid:      agentid,
+		ResponseCode: responseCode,
+		Button:       &CardButtonUpdate{ReplaceName: replaceName},
+	})
 }
 
 // PushText 推送文本消息
@@ -35,7 +108,7 @@ func (q *QiYe) Push(data interface{}) error {
 // content 消息内容，支持换行"\n"、以及超链接"A"
 //
 // users 推送的目标（多个以"|"分隔），为空表示推送到所有人
-func (q *QiYe) PushText(agentid int, content string, users string) error {
+func (q *QiYe) PushText(agentid int, content string, users string) (*PushResult, error) {
 	if users == "" {
 		users = "@all"
 	}
@@ -51,7 +124,7 @@ func (q *QiYe) PushText(agentid int, content string, users string) error {
 		},
 	}
 
-	return q.Core.push(qyTokenURL, qySendURL, data)
+	return q.send(data)
 }
 
 // PushTextMsg 推送文本消息（含标题、正文、当前时间）
@@ -63,7 +136,7 @@ func (q *QiYe) PushText(agentid int, content string, users string) error {
 // msg 消息内容，支持换行"\n"、以及超链接"A"
 //
 // users 推送的目标（多个以"|"分隔），为空表示推送到所有人
-func (q *QiYe) PushTextMsg(agentid int, title string, msg string, users string) error {
+func (q *QiYe) PushTextMsg(agentid int, title string, msg string, users string) (*PushResult, error) {
 	if users == "" {
 		users = "@all"
 	}
@@ -79,7 +152,7 @@ func (q *QiYe) PushTextMsg(agentid int, title string, msg string, users string)
 		},
 	}
 
-	return q.Core.push(qyTokenURL, qySendURL, data)
+	return q.send(data)
 }
 
 // PushCard 推送卡片消息
@@ -96,7 +169,7 @@ func (q *QiYe) PushTextMsg(agentid int, title string, msg string, users string)
 //
 // btnTxt 跳转标识文本（仅在企业微信中有效，在微信中无效）
 func (q *QiYe) PushCard(agentid int, title string, description string, users string,
-	url string, btnTxt string) error {
+	url string, btnTxt string) (*PushResult, error) {
 	if users == "" {
 		users = "@all"
 	}
@@ -119,7 +192,7 @@ func (q *QiYe) PushCard(agentid int, title string, description string, users str
 		},
 	}
 
-	return q.Core.push(qyTokenURL, qySendURL, data)
+	return q.send(data)
 }
 
 // PushMarkdown 推送 Markdown 消息（目前非企业微信不支持该类型）
@@ -131,7 +204,7 @@ func (q *QiYe) PushCard(agentid int, title string, description string, users str
 // users 推送的目标（多个以"|"分隔），为空表示推送到所有人
 //
 // @see https://developer.work.weixin.qq.com/document/path/90236#markdown%E6%B6%88%E6%81%AF
-func (q *QiYe) PushMarkdown(agentid int, content string, users string) error {
+func (q *QiYe) PushMarkdown(agentid int, content string, users string) (*PushResult, error) {
 	if users == "" {
 		users = "@all"
 	}
@@ -147,7 +220,7 @@ func (q *QiYe) PushMarkdown(agentid int, content string, users string) error {
 		},
 	}
 
-	return q.Core.push(qyTokenURL, qySendURL, data)
+	return q.send(data)
 }
 
 // 快速生成器
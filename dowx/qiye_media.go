@@ -0,0 +1,201 @@
+package dowx
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// 上传临时素材，需在其后追加素材类型
+	qyUploadURL = "/cgi-bin/media/upload?access_token=%s&type="
+	// 上传图片，得到永久有效的链接
+	qyUploadImgURL = "/cgi-bin/media/uploadimg?access_token=%s"
+)
+
+// UploadMedia 上传临时素材，3 天内有效
+//
+// mediaType 素材类型，可选 MediaImage、MediaVoice、MediaVideo、MediaFile
+//
+// path 文件的路径
+func (q *QiYe) UploadMedia(mediaType string, path string) (*MediaResult, error) {
+	return q.UploadMediaAs(mediaType, path, "")
+}
+
+// UploadMediaAs 以文件名 name 上传临时素材，接收者看到的是该文件名。name 为空""时使用路径中的文件名
+func (q *QiYe) UploadMediaAs(mediaType string, path string, name string) (*MediaResult, error) {
+	var result MediaResult
+	err := q.Core.upload(qyTokenURL, qyUploadURL+mediaType, "media", path, name, &result)
+	if err != nil {
+		return nil, fmt.Errorf("上传临时素材'%s'出错：%w", path, err)
+	}
+
+	return &result, nil
+}
+
+// UploadImg 上传图片，返回永久有效的图片链接。可用于图文消息中
+//
+// 仅支持 JPG、PNG 格式，大小在 2MB 以内
+func (q *QiYe) UploadImg(path string) (string, error) {
+	var result uploadImgResult
+	err := q.Core.upload(qyTokenURL, qyUploadImgURL, "media", path, "", &result)
+	if err != nil {
+		return "", fmt.Errorf("上传图片'%s'出错：%w", path, err)
+	}
+
+	return result.Url, nil
+}
+
+// PushImage 推送图片消息
+//
+// mediaID 图片的临时素材 ID
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushImage(agentid int, mediaID string, users string) (*PushResult, error) {
+	data := QYMsgImage{
+		QYMsg: newQYMsg(agentid, QYTypeImage, users),
+		Image: QYMsgItemMedia{MediaID: mediaID},
+	}
+
+	return q.send(data)
+}
+
+// PushVoice 推送语音消息
+//
+// mediaID 语音的临时素材 ID
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushVoice(agentid int, mediaID string, users string) (*PushResult, error) {
+	data := QYMsgVoice{
+		QYMsg: newQYMsg(agentid, QYTypeVoice, users),
+		Voice: QYMsgItemMedia{MediaID: mediaID},
+	}
+
+	return q.send(data)
+}
+
+// PushVideo 推送视频消息
+//
+// mediaID 视频的临时素材 ID
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushVideo(agentid int, mediaID string, title string, description string, users string) (*PushResult, error) {
+	data := QYMsgVideo{
+		QYMsg: newQYMsg(agentid, QYTypeVideo, users),
+		Video: QYMsgItemVideo{MediaID: mediaID, Title: title, Description: description},
+	}
+
+	return q.send(data)
+}
+
+// PushFile 上传本地文件，并作为消息推送
+//
+// 按扩展名推送：图片(.jpg、.png)为图片消息，.amr 为语音消息，.mp4 为视频消息，其它为文件消息
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushFile(agentid int, path string, users string) (*PushResult, error) {
+	return q.PushFileAs(agentid, path, "", users)
+}
+
+// PushFileAs 同 PushFile，但以文件名 name 上传、显示。name 为空""时使用路径中的文件名
+func (q *QiYe) PushFileAs(agentid int, path string, name string, users string) (*PushResult, error) {
+	if name == "" {
+		name = filepath.Base(path)
+	}
+
+	mediaType := MediaFile
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png":
+		mediaType = MediaImage
+	case ".amr":
+		mediaType = MediaVoice
+	case ".mp4":
+		mediaType = MediaVideo
+	}
+
+	media, err := q.UploadMediaAs(mediaType, path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mediaType {
+	case MediaImage:
+		return q.PushImage(agentid, media.MediaID, users)
+	case MediaVoice:
+		return q.PushVoice(agentid, media.MediaID, users)
+	case MediaVideo:
+		return q.PushVideo(agentid, media.MediaID, name, "", users)
+	}
+
+	data := QYMsgFile{
+		QYMsg: newQYMsg(agentid, QYTypeFile, users),
+		File:  QYMsgItemMedia{MediaID: media.MediaID},
+	}
+
+	return q.send(data)
+}
+
+// PushNews 推送图文消息。点击文章将跳转到其链接
+//
+// articles 最多 8 篇文章
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushNews(agentid int, articles []QYMsgItemArticle, users string) (*PushResult, error) {
+	data := QYMsgNews{
+		QYMsg: newQYMsg(agentid, QYTypeNews, users),
+		News:  QYMsgItemNews{Articles: articles},
+	}
+
+	return q.send(data)
+}
+
+// PushMpnews 推送图文消息（mpnews）。文章内容存储在企业微信中，点击后在企业微信内打开
+//
+// articles 最多 8 篇文章
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushMpnews(agentid int, articles []QYMsgItemMpArticle, users string) (*PushResult, error) {
+	data := QYMsgMpnews{
+		QYMsg:  newQYMsg(agentid, QYTypeMpnews, users),
+		Mpnews: QYMsgItemMpnews{Articles: articles},
+	}
+
+	return q.send(data)
+}
+
+// PushTemplateCard 推送模板卡片消息。仅企业微信中可见
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushTemplateCard(agentid int, card QYMsgItemTemplateCard, users string) (*PushResult, error) {
+	data := QYMsgTemplateCard{
+		QYMsg:        newQYMsg(agentid, QYTypeTemplateCard, users),
+		TemplateCard: card,
+	}
+
+	return q.send(data)
+}
+
+// PushInteractive 推送任务卡片（交互式）消息。点击按钮后，应用将收到回调事件
+//
+// users 推送的目标（多个以"|"分隔），为空表示推送到所有人
+func (q *QiYe) PushInteractive(agentid int, card QYMsgItemTaskcard, users string) (*PushResult, error) {
+	data := QYMsgInteractive{
+		QYMsg:               newQYMsg(agentid, QYTypeInteractive, users),
+		InteractiveTaskcard: card,
+	}
+
+	return q.send(data)
+}
+
+// 生成消息的公共部分
+func newQYMsg(agentid int, msgtype string, users string) *QYMsg {
+	if users == "" {
+		users = "@all"
+	}
+
+	return &QYMsg{
+		Touser:  users,
+		Msgtype: msgtype,
+		Agentid: agentid,
+	}
+}
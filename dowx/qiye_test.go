@@ -1,32 +1,231 @@
 package dowx
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/donething/utils-go/dowx/dowxtest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 var aid = 111 // 应用 ID
-var qy = NewQiYe("xxx", "xxx")
+
+// 创建指向模拟服务的 QiYe
+func newFakeQiYe(t *testing.T) (*QiYe, *dowxtest.Server) {
+	srv := dowxtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	qy := NewQiYe("corpid", "secret")
+	qy.SetAddr(srv.URL)
+	return qy, srv
+}
 
 func TestQiYe_PushText(t *testing.T) {
-	err := qy.PushText(aid, "测试文本消息，不错", "")
+	qy, srv := newFakeQiYe(t)
+
+	result, err := qy.PushText(aid, "测试文本消息，不错", "")
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	var msg QYMsgText
+	if err = srv.Messages()[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Touser != "@all" || msg.Msgtype != QYTypeText || msg.Agentid != aid || msg.Text.Content != "测试文本消息，不错" {
+		t.Errorf("推送的消息不符：%+v", msg)
+	}
+	if result.Msgid == "" {
+		t.Errorf("未返回消息 ID")
+	}
 }
 
 func TestQiYe_PushCard(t *testing.T) {
-	err := qy.PushCard(aid, "消息标题", "测试文本消息，不错"+
+	qy, srv := newFakeQiYe(t)
+
+	_, err := qy.PushCard(aid, "消息标题", "测试文本消息，不错"+
 		GenHyperlink("https://developer.work.weixin.qq.com/document/path/90236", "微信开发者中心"),
 		"", "https://www.jianshu.com/p/182ea14af3f2", "打开")
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	var msg QYMsgCard
+	if err = srv.Messages()[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Textcard.Title != "消息标题" || msg.Textcard.Btntxt != "打开" {
+		t.Errorf("推送的消息不符：%+v", msg.Textcard)
+	}
 }
 
 func TestQiYe_PushMarkdown(t *testing.T) {
-	err := qy.PushMarkdown(aid, `您的会议室已经预定，稍后会同步到**邮箱**`+
+	qy, srv := newFakeQiYe(t)
+
+	_, err := qy.PushMarkdown(aid, `您的会议室已经预定，稍后会同步到**邮箱**`+
 		GenMdInfoText("测试信息文本DIV"), "")
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	var msg QYMsgMarkdown
+	if err = srv.Messages()[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(msg.Markdown.Content, "您的会议室已经预定") {
+		t.Errorf("推送的消息不符：%+v", msg.Markdown)
+	}
+}
+
+func TestQiYe_PushErrors(t *testing.T) {
+	qy, srv := newFakeQiYe(t)
+
+	// token 过期后，刷新并重发
+	if _, err := qy.PushText(aid, "测试", ""); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	if _, err := qy.PushText(aid, "测试", ""); err != nil {
+		t.Fatal(err)
+	}
+	if srv.TokenFetches() != 2 || len(srv.Messages()) != 2 {
+		t.Errorf("应获取 2 次 token、推送 2 条消息，实际为 %d、%d", srv.TokenFetches(), len(srv.Messages()))
+	}
+
+	// 速率限制
+	srv.Fail(dowxtest.PathMessageSend, 1, dowxtest.ErrcodeRateLimit)
+	if _, err := qy.PushText(aid, "测试", ""); err == nil || IsPartialError(err) {
+		t.Errorf("应返回错误，实际为 %v", err)
+	}
+
+	// 部分接收者无效
+	srv.InvalidUsers("bad")
+	var e *PartialError
+	result, err := qy.PushText(aid, "测试", "good|bad")
+	if !errors.As(err, &e) || len(e.Users) != 1 || e.Users[0] != "bad" || result == nil {
+		t.Errorf("应返回 *PartialError，实际为 %v", err)
+	}
+
+	// 凭证错误
+	srv.AddCredential("corpid", "other")
+	srv.ExpireTokens()
+	if _, err = qy.PushText(aid, "测试", ""); err == nil {
+		t.Errorf("凭证错误时应返回错误")
+	}
+}
+
+func TestQiYe_PushFile(t *testing.T) {
+	qy, srv := newFakeQiYe(t)
+
+	dir := t.TempDir()
+	txt, png := filepath.Join(dir, "test.txt"), filepath.Join(dir, "test.png")
+	if err := os.WriteFile(txt, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(png, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其它扩展名作为文件消息推送
+	if _, err := qy.PushFile(aid, txt, ""); err != nil {
+		t.Fatal(err)
+	}
+	// 图片作为图片消息推送
+	if _, err := qy.PushFile(aid, png, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := srv.Uploads()
+	if len(uploads) != 2 || uploads[0].Type != MediaFile || uploads[0].Filename != "test.txt" ||
+		string(uploads[0].Content) != "test" || uploads[1].Type != MediaImage {
+		t.Fatalf("上传的素材不符：%+v", uploads)
+	}
+
+	var file QYMsgFile
+	if err := srv.Messages()[0].Decode(&file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Msgtype != QYTypeFile || file.File.MediaID != uploads[0].MediaID {
+		t.Errorf("推送的文件消息不符：%+v", file)
+	}
+	var image QYMsgImage
+	if err := srv.Messages()[1].Decode(&image); err != nil {
+		t.Fatal(err)
+	}
+	if image.Msgtype != QYTypeImage || image.Image.MediaID != uploads[1].MediaID {
+		t.Errorf("推送的图片消息不符：%+v", image)
+	}
+}
+
+func TestQiYe_PushNews(t *testing.T) {
+	qy, srv := newFakeQiYe(t)
+
+	_, err := qy.PushNews(aid, []QYMsgItemArticle{{
+		Title:       "测试图文消息",
+		Description: "测试图文消息的描述",
+		Url:         "https://developer.work.weixin.qq.com/document/path/90236",
+	}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg QYMsgNews
+	if err = srv.Messages()[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.News.Articles) != 1 || msg.News.Articles[0].Title != "测试图文消息" {
+		t.Errorf("推送的消息不符：%+v", msg.News)
+	}
+}
+
+func TestPushResult_Msgid(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    MsgID
+		wantErr bool
+	}{
+		{name: "字符串", text: `{"errcode":0,"msgid":"abc"}`, want: "abc"},
+		{name: "数字", text: `{"errcode":0,"msgid":200228332}`, want: "200228332"},
+		{name: "大数字", text: `{"errcode":0,"msgid":3147483647123456789}`, want: "3147483647123456789"},
+		{name: "null", text: `{"errcode":0,"msgid":null}`, want: ""},
+		{name: "缺失", text: `{"errcode":0}`, want: ""},
+		{name: "类型错误", text: `{"errcode":0,"msgid":true}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result PushResult
+			err := json.Unmarshal([]byte(tt.text), &result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Msgid != tt.want {
+				t.Errorf("Msgid got = %q, want %q", result.Msgid, tt.want)
+			}
+		})
+	}
+}
+
+func TestQiYe_Recall(t *testing.T) {
+	qy, srv := newFakeQiYe(t)
+
+	result, err := qy.PushText(aid, "即将撤回的消息", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = qy.Recall(result.Msgid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recalled := srv.Recalled(); len(recalled) != 1 || recalled[0] != string(result.Msgid) {
+		t.Errorf("撤回的消息不符：%v", recalled)
+	}
+
+	// 已撤回的消息不能再次撤回
+	if err = qy.Recall(result.Msgid); err == nil {
+		t.Errorf("再次撤回时应返回错误")
+	}
 }
@@ -1,4 +1,4 @@
-// Package dowx 微信测试号消息推送
+// Package dowx 微信测试号、公众号、企业微信的消息推送
 package dowx
 
 import (
@@ -7,10 +7,13 @@ import (
 )
 
 const (
+	// MPAddr 微信公众号、测试号接口的默认地址
+	MPAddr = "https://api.weixin.qq.com"
+
 	// 获取 Token
-	sbTokenURL = "https://api.weixin.qq.com/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s"
+	sbTokenURL = "/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s"
 	// 发送消息
-	sbSendURL = "https://api.weixin.qq.com/cgi-bin/message/template/send?access_token=%s"
+	sbSendURL = "/cgi-bin/message/template/send?access_token=%s"
 )
 
 // Sandbox 微信测试号消息推送对象
@@ -21,14 +24,14 @@ type Sandbox struct {
 // NewSandbox 获取 Sandbox 实例，以推送消息
 func NewSandbox(appid string, secret string) *Sandbox {
 	return &Sandbox{
-		Core: &Core{appid: appid, secret: secret, token: "", expires: time.Now()},
+		Core: newCore(MPAddr, appid, secret),
 	}
 }
 
 // Push 推送消息
 //
 // url 如果是有效链接，那么点击消息将会打开该链接
-func (s *Sandbox) Push(toUID string, tplID string, payload interface{}, url string) error {
+func (s *Sandbox) Push(toUID string, tplID string, payload interface{}, url string) (*PushResult, error) {
 	// 推送(POST)的数据
 	data := map[string]interface{}{"touser": toUID, "template_id": tplID,
 		"url": url, "data": payload}
@@ -39,7 +42,7 @@ func (s *Sandbox) Push(toUID string, tplID string, payload interface{}, url stri
 // PushTpl 推送模板消息
 //
 // url 如果是有效链接，那么点击消息将会打开该链接
-func (s *Sandbox) PushTpl(toUID string, tplID string, title string, msg string, url string) error {
+func (s *Sandbox) PushTpl(toUID string, tplID string, title string, msg string, url string) (*PushResult, error) {
 	payload := &SBMsg{
 		Title: SBMsgItem{Value: title + "\n"},
 		Msg:   SBMsgItem{Value: msg + "\n"},
@@ -1,14 +1,35 @@
 package dowx
 
 import (
+	"github.com/donething/utils-go/dowx/dowxtest"
+	"strings"
 	"testing"
 )
 
-var sandbox = NewSandbox("xxx", "xxx")
-
 func TestSandbox_PushTpl(t *testing.T) {
-	err := sandbox.PushTpl("xxx", "xxx", "测试标题", "测试消息内容", "")
+	srv := dowxtest.NewServer()
+	defer srv.Close()
+
+	sandbox := NewSandbox("appid", "secret")
+	sandbox.SetAddr(srv.URL)
+
+	result, err := sandbox.PushTpl("user", "tpl", "测试标题", "测试消息内容", "")
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	var msg struct {
+		Touser     string `json:"touser"`
+		TemplateID string `json:"template_id"`
+		Data       SBMsg  `json:"data"`
+	}
+	if err = srv.Messages()[0].Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Touser != "user" || msg.TemplateID != "tpl" || !strings.HasPrefix(msg.Data.Title.Value, "测试标题") {
+		t.Errorf("推送的消息不符：%+v", msg)
+	}
+	if result.Msgid == "" {
+		t.Errorf("未返回消息 ID")
+	}
 }
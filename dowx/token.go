@@ -0,0 +1,145 @@
+package dowx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Token 缓存的 access_token
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	Expires     time.Time `json:"expires"` // 过期时间，已提前了几分钟以容错
+}
+
+// Valid token 是否存在且未过期
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && time.Now().Before(t.Expires)
+}
+
+// TokenStore token 的缓存。实现需可在多个协程中使用
+type TokenStore interface {
+	// Get 获取键对应的 token。不存在时返回 nil
+	Get(key string) (*Token, error)
+	// Set 保存 token
+	Set(key string, token *Token) error
+}
+
+// MemoryTokenStore 在内存中缓存 token，进程退出后失效
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]*Token
+}
+
+// NewMemoryTokenStore 创建内存中的 token 缓存
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]*Token)}
+}
+
+// Get 获取键对应的 token
+func (s *MemoryTokenStore) Get(key string) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tokens[key], nil
+}
+
+// Set 保存 token
+func (s *MemoryTokenStore) Set(key string, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[key] = token
+	return nil
+}
+
+// FileTokenStore 在 JSON 文件中缓存 token，可在多个进程、程序重启后共用
+//
+// 未使用 dobolt，是因为 boltdb 打开期间会独占数据库文件，其它进程无法读取
+//
+// 写入时先写临时文件再重命名，读取时不会读到写了一半的内容；多个进程同时写入时，后写入的覆盖先写入的，
+// 只会导致多获取一次 token
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileTokenStore 创建保存在 path 的 token 缓存。文件不存在时将在保存时创建
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Get 获取键对应的 token
+func (s *FileTokenStore) Get(key string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens[key], nil
+}
+
+// Set 保存 token
+func (s *FileTokenStore) Set(key string, token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[key] = token
+
+	bs, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 token 出错：%w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件出错：%w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(bs)
+	if errC := tmp.Close(); err == nil {
+		err = errC
+	}
+	if err != nil {
+		return fmt.Errorf("写入临时文件出错：%w", err)
+	}
+
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		return fmt.Errorf("保存 token 文件出错：%w", err)
+	}
+
+	return nil
+}
+
+// 读取文件中的所有 token
+func (s *FileTokenStore) read() (map[string]*Token, error) {
+	tokens := make(map[string]*Token)
+
+	bs, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 token 文件出错：%w", err)
+	}
+
+	err = json.Unmarshal(bs, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("解析 token 文件出错：%w", err)
+	}
+
+	return tokens, nil
+}
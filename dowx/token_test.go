@@ -0,0 +1,96 @@
+package dowx
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 模拟获取 token、推送消息的服务。第 n 次获取的 token 为"tn"，只有最新的 token 有效
+func newTokenServer(t *testing.T) (*httptest.Server, *int32) {
+	var fetched int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gettoken":
+			// 延迟响应，使并发的请求能同时等待
+			time.Sleep(20 * time.Millisecond)
+			n := atomic.AddInt32(&fetched, 1)
+			fmt.Fprintf(w, `{"errcode":0,"access_token":"t%d","expires_in":7200}`, n)
+		case "/send":
+			if r.URL.Query().Get("access_token") != fmt.Sprintf("t%d", atomic.LoadInt32(&fetched)) {
+				w.Write([]byte(`{"errcode":42001,"errmsg":"access_token expired"}`))
+				return
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &fetched
+}
+
+func TestCore_accessToken(t *testing.T) {
+	srv, fetched := newTokenServer(t)
+	tokenURL := "/gettoken?corpid=%s&corpsecret=%s"
+	core := newCore(srv.URL, "id", "secret")
+
+	// 并发获取时只请求一次
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := core.accessToken(tokenURL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if *fetched != 1 {
+		t.Errorf("应获取 1 次 token，实际获取 %d 次", *fetched)
+	}
+
+	// token 失效后，刷新并重发
+	atomic.AddInt32(fetched, 1)
+	_, err := core.push(tokenURL, "/send?access_token=%s", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *fetched != 3 {
+		t.Errorf("应共获取 3 次 token，实际获取 %d 次", *fetched)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	srv, fetched := newTokenServer(t)
+	tokenURL := "/gettoken?corpid=%s&corpsecret=%s"
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	// 模拟两个进程共用缓存文件
+	for i := 0; i < 2; i++ {
+		core := newCore(srv.URL, "id", "secret")
+		core.SetTokenStore(NewFileTokenStore(path))
+		token, err := core.accessToken(tokenURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "t1" {
+			t.Errorf("token 不符：%s", token)
+		}
+	}
+	if *fetched != 1 {
+		t.Errorf("应获取 1 次 token，实际获取 %d 次", *fetched)
+	}
+
+	// 不同的 secret 不共用 token
+	core := newCore(srv.URL, "id", "secret2")
+	core.SetTokenStore(NewFileTokenStore(path))
+	if token, _ := core.accessToken(tokenURL); token != "t2" {
+		t.Errorf("token 不符：%s", token)
+	}
+}